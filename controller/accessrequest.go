@@ -0,0 +1,354 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	v2 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// defaultAccessRequestMaxDuration is used for groups whose access request policy does not limit the duration
+const defaultAccessRequestMaxDuration = 8 * time.Hour
+
+func (c *Controller) enqueueAccessRequest(obj interface{}) {
+	if objectRef, err := cache.DeletionHandlingObjectToName(obj); err != nil {
+		utilruntime.HandleError(err)
+		return
+	} else {
+		c.accessRequestWorkqueue.Add(objectRef)
+	}
+}
+
+// enqueueAccessRequestsOfUser enqueues the open access requests of, or approved by, the given user, as they depend on
+// whether the user exists and on the memberships of the approver
+func (c *Controller) enqueueAccessRequestsOfUser(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	user, ok := obj.(*v1alpha2.User)
+	if !ok {
+		return
+	}
+
+	requests, err := c.accessRequestLister.AccessRequests(user.Namespace).List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	for _, request := range requests {
+		if request.Status.Phase == v1alpha2.AccessRequestActive || request.Status.Phase == v1alpha2.AccessRequestExpired {
+			continue
+		}
+
+		if request.Spec.User == user.Name || request.Spec.ApprovedBy == user.Name {
+			c.accessRequestWorkqueue.Add(cache.ObjectName{Namespace: request.Namespace, Name: request.Name})
+		}
+	}
+}
+
+func (c *Controller) runAccessRequestWorker(ctx context.Context) {
+	for c.processNextAccessRequestWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextAccessRequestWorkItem(ctx context.Context) bool {
+	objRef, shutdown := c.accessRequestWorkqueue.Get()
+	logger := klog.FromContext(ctx)
+
+	if shutdown {
+		return false
+	}
+
+	defer c.accessRequestWorkqueue.Done(objRef)
+	defer c.heartbeats.begin(kindAccessRequest)()
+
+	start := time.Now()
+	err := c.syncAccessRequestHandler(ctx, objRef.(cache.ObjectName))
+	observeReconcile(kindAccessRequest, start, err)
+
+	if err == nil {
+		c.accessRequestWorkqueue.Forget(objRef)
+		logger.Info("Successfully synced", "objectName", objRef)
+		return true
+	}
+
+	logger.Error(err, "Error syncing; requeuing for later retry", "objectReference", objRef)
+
+	c.accessRequestWorkqueue.AddRateLimited(objRef)
+	return true
+}
+
+func (c *Controller) syncAccessRequestHandler(ctx context.Context, objectRef cache.ObjectName) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "objectRef", objectRef)
+
+	request, err := c.accessRequestLister.AccessRequests(objectRef.Namespace).Get(objectRef.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("AccessRequest no longer exists, revoking the memberships it granted")
+			return c.revokeDeletedAccessRequest(ctx, objectRef, "")
+		}
+
+		return err
+	}
+
+	// a request that was deleted and created again under the same name must not inherit the memberships of its predecessor
+	if err = c.revokeDeletedAccessRequest(ctx, objectRef, request.UID); err != nil {
+		return err
+	}
+
+	status := *request.Status.DeepCopy()
+	status.ObservedGeneration = request.Generation
+
+	err = c.reconcileAccessRequest(ctx, request, &status)
+	setReadyCondition(&status.Conditions, request.Generation, err)
+
+	if statusErr := c.updateAccessRequestStatus(ctx, request, status); statusErr != nil {
+		logger.Error(statusErr, "Error while updating AccessRequest status", "accessRequest", request.Name)
+		if err == nil {
+			return statusErr
+		}
+	}
+
+	return err
+}
+
+// reconcileAccessRequest moves an AccessRequest through its phases. Pending requests are checked against the access
+// request policy of their group and granted once a valid approver approved them, which adds a time-bound membership to
+// the target User. Active requests remove that membership again once their window has passed.
+func (c *Controller) reconcileAccessRequest(ctx context.Context, request *v1alpha2.AccessRequest, status *v1alpha2.AccessRequestStatus) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "accessRequest", request.Name, "user", request.Spec.User, "group", request.Spec.Group)
+
+	switch status.Phase {
+	case v1alpha2.AccessRequestExpired:
+		return nil
+	case v1alpha2.AccessRequestActive:
+		return c.expireAccessRequest(ctx, request, status)
+	}
+
+	deny := func(message string) error {
+		if status.Phase != v1alpha2.AccessRequestDenied {
+			logger.Info("Denying AccessRequest", "reason", message)
+			c.recorder.Event(request, v2.EventTypeWarning, ReasonDenied, message)
+		}
+		status.Phase = v1alpha2.AccessRequestDenied
+		setApprovedCondition(status, request.Generation, v3.ConditionFalse, ReasonDenied, message)
+		return nil
+	}
+
+	group, err := c.groupLister.Groups(request.Namespace).Get(request.Spec.Group)
+	if errors.IsNotFound(err) {
+		return deny(fmt.Sprintf("group %v does not exist", request.Spec.Group))
+	}
+	if err != nil {
+		return err
+	}
+
+	policy := group.Spec.AccessRequests
+	if policy == nil {
+		return deny(fmt.Sprintf("group %v does not allow access requests", group.Name))
+	}
+
+	maxDuration := defaultAccessRequestMaxDuration
+	if policy.MaxDuration != nil {
+		maxDuration = policy.MaxDuration.Duration
+	}
+
+	if request.Spec.Duration.Duration <= 0 || request.Spec.Duration.Duration > maxDuration {
+		return deny(fmt.Sprintf("duration has to be positive and at most %v for group %v", maxDuration, group.Name))
+	}
+
+	user, err := c.userLister.Users(request.Namespace).Get(request.Spec.User)
+	if errors.IsNotFound(err) {
+		return deny(fmt.Sprintf("user %v does not exist", request.Spec.User))
+	}
+	if err != nil {
+		return err
+	}
+
+	status.Phase = v1alpha2.AccessRequestPending
+
+	if request.Spec.ApprovedBy == "" {
+		setApprovedCondition(status, request.Generation, v3.ConditionFalse, ReasonAwaitingApproval, fmt.Sprintf("Waiting for a member of group %v to approve", policy.ApproverGroup))
+		return nil
+	}
+
+	// without the webhook, anybody who can update the request could name an arbitrary approver
+	if !c.options.TrustAccessRequestApprovals {
+		message := "approvals are only accepted once the validating webhook is installed, start the controller with -trust-access-request-approvals"
+		if condition := meta.FindStatusCondition(status.Conditions, v1alpha2.ConditionApproved); condition == nil || condition.Reason != ReasonApprovalNotAuthenticated {
+			c.recorder.Event(request, v2.EventTypeWarning, ReasonApprovalNotAuthenticated, message)
+		}
+		setApprovedCondition(status, request.Generation, v3.ConditionFalse, ReasonApprovalNotAuthenticated, message)
+		return nil
+	}
+
+	if problem, err := c.checkApprover(request, policy.ApproverGroup); err != nil {
+		return err
+	} else if problem != "" {
+		if condition := meta.FindStatusCondition(status.Conditions, v1alpha2.ConditionApproved); condition == nil || condition.Message != problem {
+			c.recorder.Event(request, v2.EventTypeWarning, ReasonInvalidApprover, problem)
+		}
+		setApprovedCondition(status, request.Generation, v3.ConditionFalse, ReasonInvalidApprover, problem)
+		return nil
+	}
+
+	// metav1.Time is serialised with a precision of seconds
+	grantedAt := time.Now().Truncate(time.Second)
+	expiresAt := v3.NewTime(grantedAt.Add(request.Spec.Duration.Duration))
+
+	// the membership may already have been granted by an earlier attempt whose status update failed
+	if index := slices.IndexFunc(user.Spec.Memberships, grantedBy(request)); index >= 0 && user.Spec.Memberships[index].ExpiresAt != nil {
+		expiresAt = *user.Spec.Memberships[index].ExpiresAt
+		grantedAt = expiresAt.Add(-request.Spec.Duration.Duration)
+	} else {
+		updatedUser := user.DeepCopy()
+		updatedUser.Spec.Memberships = append(updatedUser.Spec.Memberships, v1alpha2.GroupMembership{
+			Group:     group.Name,
+			ExpiresAt: &expiresAt,
+			AccessRequest: &v1alpha2.AccessRequestReference{
+				Name: request.Name,
+				UID:  request.UID,
+			},
+		})
+
+		if _, err = c.clientSet.Perm8sV1alpha1().Users(user.Namespace).Update(ctx, updatedUser, v3.UpdateOptions{FieldManager: FieldManager}); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Granted membership of AccessRequest", "approvedBy", request.Spec.ApprovedBy, "expiresAt", expiresAt)
+	message := fmt.Sprintf("Granted membership of user %v in group %v until %v, approved by %v", user.Name, group.Name, expiresAt.Format(time.RFC3339), request.Spec.ApprovedBy)
+	c.recorder.Event(request, v2.EventTypeNormal, ReasonApproved, message)
+	c.recorder.Event(user, v2.EventTypeNormal, ReasonApproved, message+" through AccessRequest "+request.Name)
+
+	status.Phase = v1alpha2.AccessRequestActive
+	status.ApprovedBy = request.Spec.ApprovedBy
+	status.GrantedAt = &v3.Time{Time: grantedAt}
+	status.ExpiresAt = &expiresAt
+	setApprovedCondition(status, request.Generation, v3.ConditionTrue, ReasonApproved, "Approved by "+request.Spec.ApprovedBy)
+
+	c.accessRequestWorkqueue.AddAfter(cache.ObjectName{Namespace: request.Namespace, Name: request.Name}, time.Until(expiresAt.Time))
+	return nil
+}
+
+// expireAccessRequest removes the membership granted by an active AccessRequest from its User once its window has passed
+func (c *Controller) expireAccessRequest(ctx context.Context, request *v1alpha2.AccessRequest, status *v1alpha2.AccessRequestStatus) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "accessRequest", request.Name, "user", request.Spec.User, "group", request.Spec.Group)
+
+	if status.ExpiresAt == nil {
+		return fmt.Errorf("active AccessRequest %v has no expiry", request.Name)
+	}
+
+	if remaining := time.Until(status.ExpiresAt.Time); remaining > 0 {
+		c.accessRequestWorkqueue.AddAfter(cache.ObjectName{Namespace: request.Namespace, Name: request.Name}, remaining)
+		return nil
+	}
+
+	user, err := c.userLister.Users(request.Namespace).Get(request.Spec.User)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if user != nil {
+		if err = c.revokeMemberships(ctx, user, grantedBy(request), request.Name); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("AccessRequest expired, membership revoked")
+	c.recorder.Event(request, v2.EventTypeNormal, ReasonRevoked, fmt.Sprintf("Revoked membership of user %v in group %v", request.Spec.User, request.Spec.Group))
+	status.Phase = v1alpha2.AccessRequestExpired
+	return nil
+}
+
+// revokeDeletedAccessRequest removes the memberships that deleted AccessRequests of the given name granted before they
+// expire. Memberships granted by the request that currently exists under that name, identified by its UID, are kept
+func (c *Controller) revokeDeletedAccessRequest(ctx context.Context, objectRef cache.ObjectName, currentUID types.UID) error {
+	users, err := c.userLister.Users(objectRef.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	granted := func(membership v1alpha2.GroupMembership) bool {
+		return membership.AccessRequest != nil && membership.AccessRequest.Name == objectRef.Name && membership.AccessRequest.UID != currentUID
+	}
+
+	for _, user := range users {
+		if err = c.revokeMemberships(ctx, user, granted, objectRef.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revokeMemberships removes the memberships of the user that were granted by the named AccessRequest
+func (c *Controller) revokeMemberships(ctx context.Context, user *v1alpha2.User, granted func(v1alpha2.GroupMembership) bool, requestName string) error {
+	if !slices.ContainsFunc(user.Spec.Memberships, granted) {
+		return nil
+	}
+
+	updatedUser := user.DeepCopy()
+	updatedUser.Spec.Memberships = slices.DeleteFunc(updatedUser.Spec.Memberships, granted)
+
+	if _, err := c.clientSet.Perm8sV1alpha1().Users(user.Namespace).Update(ctx, updatedUser, v3.UpdateOptions{FieldManager: FieldManager}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	c.recorder.Event(user, v2.EventTypeNormal, ReasonRevoked, fmt.Sprintf("Revoked membership granted by AccessRequest %v", requestName))
+	return nil
+}
+
+// grantedBy matches the membership granted by the request. The UID tells it apart from a request of the same name
+// that was deleted and created again
+func grantedBy(request *v1alpha2.AccessRequest) func(v1alpha2.GroupMembership) bool {
+	return func(membership v1alpha2.GroupMembership) bool {
+		return membership.AccessRequest != nil && membership.AccessRequest.UID == request.UID
+	}
+}
+
+// checkApprover returns why the approver of the request is not allowed to approve it, or an empty string if it is
+func (c *Controller) checkApprover(request *v1alpha2.AccessRequest, approverGroup string) (string, error) {
+	if request.Spec.ApprovedBy == request.Spec.User {
+		return "users cannot approve their own access requests", nil
+	}
+
+	approver, err := c.userLister.Users(request.Namespace).Get(request.Spec.ApprovedBy)
+	if errors.IsNotFound(err) {
+		return fmt.Sprintf("approver %v does not exist", request.Spec.ApprovedBy), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	memberships, _, _ := activeGroupMemberships(approver, time.Now())
+	effectiveGroups, _ := c.expandGroups(approver.Namespace, memberships)
+	if approver.Spec.Suspended || !slices.Contains(effectiveGroups, approverGroup) {
+		return fmt.Sprintf("approver %v is not a member of the approver group %v", approver.Name, approverGroup), nil
+	}
+
+	return "", nil
+}
+
+func setApprovedCondition(status *v1alpha2.AccessRequestStatus, generation int64, conditionStatus v3.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&status.Conditions, v3.Condition{
+		Type:               v1alpha2.ConditionApproved,
+		Status:             conditionStatus,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
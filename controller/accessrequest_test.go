@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+	"perm8s/pkg/generated/clientset/versioned/fake"
+	listers "perm8s/pkg/generated/listers/perm8s/v1alpha1"
+)
+
+func TestRevokeDeletedAccessRequest(t *testing.T) {
+	membership := func(group string, uid types.UID) v1alpha2.GroupMembership {
+		return v1alpha2.GroupMembership{Group: group, AccessRequest: &v1alpha2.AccessRequestReference{Name: "oncall", UID: uid}}
+	}
+
+	tests := []struct {
+		name       string
+		currentUID types.UID
+		want       []v1alpha2.GroupMembership
+	}{
+		{name: "deleted request", currentUID: "", want: []v1alpha2.GroupMembership{{Group: "developers"}}},
+		{name: "request created again", currentUID: "new", want: []v1alpha2.GroupMembership{{Group: "developers"}, membership("admins", "new")}},
+		{name: "request still existing", currentUID: "old", want: []v1alpha2.GroupMembership{{Group: "developers"}, membership("operators", "old")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := &v1alpha2.User{
+				ObjectMeta: v3.ObjectMeta{Name: "jane", Namespace: "team"},
+				Spec: v1alpha2.UserSpec{Memberships: []v1alpha2.GroupMembership{
+					{Group: "developers"},
+					membership("operators", "old"),
+					membership("admins", "new"),
+				}},
+			}
+
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			if err := indexer.Add(user); err != nil {
+				t.Fatalf("indexer.Add() error = %v", err)
+			}
+
+			clientSet := fake.NewSimpleClientset(user)
+			c := &Controller{clientSet: clientSet, userLister: listers.NewUserLister(indexer), recorder: record.NewFakeRecorder(10)}
+
+			if err := c.revokeDeletedAccessRequest(context.Background(), cache.ObjectName{Namespace: "team", Name: "oncall"}, test.currentUID); err != nil {
+				t.Fatalf("revokeDeletedAccessRequest() error = %v", err)
+			}
+
+			updated, err := clientSet.Perm8sV1alpha1().Users("team").Get(context.Background(), "jane", v3.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if !reflect.DeepEqual(updated.Spec.Memberships, test.want) {
+				t.Errorf("Memberships = %+v, want %+v", updated.Spec.Memberships, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	v1 "k8s.io/api/rbac/v1"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// bindingTemplate describes who the roles of a group are granted to through the bindings rendered by groupBindings
+type bindingTemplate struct {
+	// name is the name of the binding of the generated ClusterRole, the bindings of the other roles get a suffix. The
+	// suffixes are separated by a colon, which cannot be part of the names of Users and Groups, so they never collide
+	// with the bindings of another group
+	name     string
+	labels   map[string]string
+	subjects []v1.Subject
+	// owner is set as the controller of ClusterRoleBindings
+	owner *v3.OwnerReference
+}
+
+// groupBindings renders the ClusterRoleBindings and RoleBindings that grant the roles of the group to the subjects of
+// the template. The generated ClusterRole and the referenced ClusterRoles are bound cluster wide for cluster groups and
+// in every namespace of the group otherwise. Cluster permissions are always bound cluster wide, namespace permissions
+// and referenced Roles in their own namespaces.
+func (c *Controller) groupBindings(group *v1alpha2.Group, template bindingTemplate) ([]*v1.ClusterRoleBinding, []*v1.RoleBinding, error) {
+	var clusterRoleBindings []*v1.ClusterRoleBinding
+	var roleBindings []*v1.RoleBinding
+
+	type namedRoleRef struct {
+		name    string
+		roleRef v1.RoleRef
+	}
+
+	var clusterRoles []namedRoleRef
+	if hasGeneratedClusterRole(group) {
+		// a ClusterRole of the same name that someone else created, f.e. a built-in one, is never bound
+		if !clusterRoleConflicts(group, group.Name) {
+			clusterRoles = append(clusterRoles, namedRoleRef{template.name, clusterRoleRef(group.Name)})
+		}
+	} else if hasGeneratedRole(group) {
+		// the Role of the group carries its name in every namespace of the group
+		namespaces, err := c.groupNamespaces(group)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, namespace := range namespaces {
+			// a Role of the same name that someone else created would grant its permissions to the members
+			if roleConflicts(group, namespace, group.Name) {
+				continue
+			}
+			roleBindings = append(roleBindings, template.roleBinding(namespace, template.name, roleRef(group.Name)))
+		}
+	}
+	for _, clusterRoleName := range group.Spec.ClusterRoleRefs {
+		clusterRoles = append(clusterRoles, namedRoleRef{template.name + ":clusterrole:" + clusterRoleName, clusterRoleRef(clusterRoleName)})
+	}
+
+	if group.Spec.ClusterGroup {
+		for _, clusterRole := range clusterRoles {
+			clusterRoleBindings = append(clusterRoleBindings, template.clusterRoleBinding(clusterRole.name, clusterRole.roleRef))
+		}
+	} else if len(clusterRoles) > 0 {
+		namespaces, err := c.groupNamespaces(group)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, namespace := range namespaces {
+			for _, clusterRole := range clusterRoles {
+				roleBindings = append(roleBindings, template.roleBinding(namespace, clusterRole.name, clusterRole.roleRef))
+			}
+		}
+	}
+
+	// cluster permissions are granted cluster wide, whether the group is a cluster group or not
+	if len(group.Spec.ClusterPermissions) > 0 && !clusterRoleConflicts(group, ClusterPermissionsClusterRoleName(group)) {
+		clusterRoleBindings = append(clusterRoleBindings, template.clusterRoleBinding(template.name+":cluster", clusterRoleRef(ClusterPermissionsClusterRoleName(group))))
+	}
+
+	// namespace permissions apply to their own namespaces, whether the group is a cluster group or not
+	for _, permissions := range group.Spec.NamespacePermissions {
+		namespaces, err := c.resolveNamespaces(namespaceSelection{permissions.Namespaces, permissions.NamespaceSelector})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ref := namespacePermissionsRoleRef(group, permissions)
+		for _, namespace := range namespaces {
+			if ref.Kind == "Role" && roleConflicts(group, namespace, ref.Name) {
+				continue
+			}
+			if ref.Kind == "ClusterRole" && permissions.ClusterRoleRef == "" && clusterRoleConflicts(group, ref.Name) {
+				continue
+			}
+			roleBindings = append(roleBindings, template.roleBinding(namespace, template.name+":ns:"+permissions.Name, ref))
+		}
+	}
+
+	// Roles only exist within their namespace, so they are bound there regardless of the scope of the group
+	for _, ref := range group.Spec.RoleRefs {
+		roleBindings = append(roleBindings, template.roleBinding(ref.Namespace, template.name+":role:"+ref.Name, roleRef(ref.Name)))
+	}
+
+	return clusterRoleBindings, roleBindings, nil
+}
+
+func (t bindingTemplate) clusterRoleBinding(name string, roleRef v1.RoleRef) *v1.ClusterRoleBinding {
+	clusterRoleBinding := &v1.ClusterRoleBinding{
+		ObjectMeta: v3.ObjectMeta{
+			Name:   name,
+			Labels: t.labels,
+		},
+		Subjects: t.subjects,
+		RoleRef:  roleRef,
+	}
+
+	if t.owner != nil {
+		clusterRoleBinding.OwnerReferences = []v3.OwnerReference{*t.owner}
+	}
+
+	return clusterRoleBinding
+}
+
+func (t bindingTemplate) roleBinding(namespace string, name string, roleRef v1.RoleRef) *v1.RoleBinding {
+	return &v1.RoleBinding{
+		ObjectMeta: v3.ObjectMeta{
+			Name:      name,
+			Labels:    t.labels,
+			Namespace: namespace,
+		},
+		Subjects: t.subjects,
+		RoleRef:  roleRef,
+	}
+}
+
+func roleRef(name string) v1.RoleRef {
+	return v1.RoleRef{
+		Kind:     "Role",
+		Name:     name,
+		APIGroup: "rbac.authorization.k8s.io",
+	}
+}
+
+func clusterRoleRef(name string) v1.RoleRef {
+	return v1.RoleRef{
+		Kind:     "ClusterRole",
+		Name:     name,
+		APIGroup: "rbac.authorization.k8s.io",
+	}
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"testing"
+
+	v4 "k8s.io/api/rbac/v1"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+func TestGroupBindingNamesDoNotCollide(t *testing.T) {
+	// every group renders the bindings a group named like one of the suffixes of the other would render
+	groups := []*v1alpha2.Group{
+		{
+			ObjectMeta: v3.ObjectMeta{Name: "ops", Namespace: "team"},
+			Spec: v1alpha2.GroupSpec{
+				ClusterGroup:       true,
+				Permissions:        []v4.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}},
+				ClusterPermissions: []v4.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/metrics"}}},
+				ClusterRoleRefs:    []string{"view"},
+			},
+		},
+		{
+			ObjectMeta: v3.ObjectMeta{Name: "ops-cluster", Namespace: "team"},
+			Spec: v1alpha2.GroupSpec{
+				ClusterGroup: true,
+				Permissions:  []v4.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}},
+			},
+		},
+		{
+			ObjectMeta: v3.ObjectMeta{Name: "ops-clusterrole-view", Namespace: "team"},
+			Spec: v1alpha2.GroupSpec{
+				ClusterGroup: true,
+				Permissions:  []v4.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}},
+			},
+		},
+	}
+
+	c := &Controller{}
+	seen := map[string]string{}
+
+	for _, group := range groups {
+		clusterRoleNames := []string{group.Name, PermissionsClusterRoleName(group), ClusterPermissionsClusterRoleName(group)}
+		for _, name := range clusterRoleNames {
+			if other, ok := seen["ClusterRole/"+name]; ok {
+				t.Errorf("ClusterRole %v of group %v collides with group %v", name, group.Name, other)
+			}
+			seen["ClusterRole/"+name] = group.Name
+		}
+
+		clusterRoleBindings, _, err := c.groupBindings(group, bindingTemplate{name: group.Name})
+		if err != nil {
+			t.Fatalf("groupBindings() error = %v", err)
+		}
+
+		for _, clusterRoleBinding := range clusterRoleBindings {
+			if other, ok := seen["ClusterRoleBinding/"+clusterRoleBinding.Name]; ok {
+				t.Errorf("ClusterRoleBinding %v of group %v collides with group %v", clusterRoleBinding.Name, group.Name, other)
+			}
+			seen["ClusterRoleBinding/"+clusterRoleBinding.Name] = group.Name
+		}
+	}
+}
@@ -4,11 +4,15 @@ import (
     "context"
     "fmt"
     "golang.org/x/time/rate"
+    "maps"
     v2 "k8s.io/api/core/v1"
+    v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
     utilruntime "k8s.io/apimachinery/pkg/util/runtime"
     "k8s.io/apimachinery/pkg/util/wait"
+    kubeinformers "k8s.io/client-go/informers"
     "k8s.io/client-go/kubernetes"
     "k8s.io/client-go/kubernetes/scheme"
+    corelisters "k8s.io/client-go/listers/core/v1"
     v1 "k8s.io/client-go/kubernetes/typed/core/v1"
     "k8s.io/client-go/tools/cache"
     "k8s.io/client-go/tools/record"
@@ -18,32 +22,71 @@ import (
     permscheme "perm8s/pkg/generated/clientset/versioned/scheme"
     "perm8s/pkg/generated/informers/externalversions/perm8s/v1alpha1"
     listers "perm8s/pkg/generated/listers/perm8s/v1alpha1"
+    "sync"
     "time"
 )
 
 type Controller struct {
     kubeclientset kubernetes.Interface
     clientSet  clientset.Interface
-    apiClient  *v1.CoreV1Client
+    apiClient  v1.CoreV1Interface
     userLister listers.UserLister
     groupLister listers.GroupLister
     syncSourceLister listers.SynchronisationSourceLister
+    accessRequestLister listers.AccessRequestLister
     usersSynced   cache.InformerSynced
     groupsSynced cache.InformerSynced
     syncSourcesSynced cache.InformerSynced
+    namespaceLister corelisters.NamespaceLister
+    namespacesSynced cache.InformerSynced
+    accessRequestsSynced cache.InformerSynced
+    // managedObjectsSynced contains the sync functions of the informers watching the objects created by the controller
+    managedObjectsSynced []cache.InformerSynced
     userWorkqueue workqueue.RateLimitingInterface
     groupWorkqueue      workqueue.RateLimitingInterface
     syncSourceWorkqueue workqueue.RateLimitingInterface
+    accessRequestWorkqueue workqueue.RateLimitingInterface
 
     recorder record.EventRecorder
+    options  Options
+    // heartbeats tracks the items in progress for the liveness probe
+    heartbeats workerHeartbeats
+}
+
+// Options contains the controller wide settings that are configured through flags
+type Options struct {
+    // ClusterName is the name of the cluster and context in generated kubeconfigs
+    ClusterName string
+    // ClusterServer is the URL of the API server that is written into generated kubeconfigs
+    ClusterServer string
+    // TokenMode is the default token mode of users that do not configure one themselves
+    TokenMode string
+    // TokenTTL is the default lifetime of tokens issued through the TokenRequest API
+    TokenTTL time.Duration
+    // TokenAudiences are the default audiences of tokens issued through the TokenRequest API
+    TokenAudiences []string
+    // OIDCUsernamePrefix is prepended to the usernames of external identities that do not configure a prefix themselves
+    OIDCUsernamePrefix string
+    // OIDCGroupsPrefix is prepended to the groups of external identities that do not configure a prefix themselves
+    OIDCGroupsPrefix string
+    // TrustAccessRequestApprovals is set once the validating webhook is installed, which is the only place the approver
+    // of an AccessRequest is authenticated. AccessRequests are not granted without it
+    TrustAccessRequestApprovals bool
+    // WorkerStuckTimeout is how long a worker may spend on a single item before the liveness probe fails
+    WorkerStuckTimeout time.Duration
+    // LeaderElection configures the Lease that decides which replica runs the workers
+    LeaderElection LeaderElectionOptions
 }
 
 func NewController(
     ctx context.Context,
     kubeclientset kubernetes.Interface,
     clientSet clientset.Interface,
-    apiClient *v1.CoreV1Client,
-    version v1alpha1.Interface) *Controller {
+    apiClient v1.CoreV1Interface,
+    version v1alpha1.Interface,
+    managedInformerFactory kubeinformers.SharedInformerFactory,
+    kubeInformerFactory kubeinformers.SharedInformerFactory,
+    options Options) *Controller {
     logger := klog.FromContext(ctx)
     
     utilruntime.Must(permscheme.AddToScheme(scheme.Scheme))
@@ -53,10 +96,14 @@ func NewController(
     eventBroadcaster.StartStructuredLogging(0)
     eventBroadcaster.StartRecordingToSink(&v1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
     recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v2.EventSource{Component: controllerAgentName})
-    ratelimiter := workqueue.NewMaxOfRateLimiter(
-        workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, 1000*time.Second),
-        &workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(50), 300)},
-    )
+    // every queue gets its own rate limiter, the failure counts are tracked per item and the items of the queues overlap
+    newWorkqueue := func(name string) workqueue.RateLimitingInterface {
+        ratelimiter := workqueue.NewMaxOfRateLimiter(
+            workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, 1000*time.Second),
+            &workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(50), 300)},
+        )
+        return workqueue.NewRateLimitingQueueWithConfig(ratelimiter, workqueue.RateLimitingQueueConfig{Name: name})
+    }
 
     controller := &Controller{
         kubeclientset:       kubeclientset,
@@ -65,71 +112,210 @@ func NewController(
         userLister:          version.Users().Lister(),
         groupLister:         version.Groups().Lister(),
         syncSourceLister :   version.SynchronisationSources().Lister(),
+        accessRequestLister: version.AccessRequests().Lister(),
         usersSynced:         version.Users().Informer().HasSynced,
         groupsSynced:        version.Groups().Informer().HasSynced,
         syncSourcesSynced:   version.SynchronisationSources().Informer().HasSynced,
-        userWorkqueue:       workqueue.NewRateLimitingQueue(ratelimiter),
-        groupWorkqueue:      workqueue.NewRateLimitingQueue(ratelimiter),
-        syncSourceWorkqueue: workqueue.NewRateLimitingQueue(ratelimiter),
+        accessRequestsSynced: version.AccessRequests().Informer().HasSynced,
+        namespaceLister:     kubeInformerFactory.Core().V1().Namespaces().Lister(),
+        namespacesSynced:    kubeInformerFactory.Core().V1().Namespaces().Informer().HasSynced,
+        userWorkqueue:       newWorkqueue("users"),
+        groupWorkqueue:      newWorkqueue("groups"),
+        syncSourceWorkqueue: newWorkqueue("synchronisationsources"),
+        accessRequestWorkqueue: newWorkqueue("accessrequests"),
         recorder:            recorder,
+        options:             options,
     }
 
     logger.Info("Setting up event handlers")
 
     version.Users().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-        AddFunc: controller.enqueueUser,
+        AddFunc: func(obj interface{}) {
+            controller.enqueueUser(obj)
+            controller.enqueueGroupsOfUser(obj)
+            controller.enqueueAccessRequestsOfUser(obj)
+        },
         UpdateFunc: func(old, new interface{}) {
+            if !needsReconcile(old, new) {
+                return
+            }
             controller.enqueueUser(new)
+            controller.enqueueGroupsOfUser(old)
+            controller.enqueueGroupsOfUser(new)
+            controller.enqueueAccessRequestsOfUser(new)
+        },
+        DeleteFunc: func(obj interface{}) {
+            controller.enqueueGroupsOfUser(obj)
+            controller.enqueueAccessRequestsOfUser(obj)
         },
     })
     
     version.Groups().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-        AddFunc: controller.enqueueGroup,
+        AddFunc: func(obj interface{}) {
+            controller.enqueueGroup(obj)
+            controller.enqueueIncludingGroups(obj)
+            controller.enqueueUsersOfGroup(obj)
+        },
         UpdateFunc: func(old, new interface{}) {
+            if conflictsChanged(old, new) {
+                controller.enqueueIncludingGroups(new)
+                controller.enqueueUsersOfGroup(new)
+            }
+            if !needsReconcile(old, new) {
+                return
+            }
             controller.enqueueGroup(new)
+            if bindingsChanged(old, new) {
+                controller.enqueueIncludingGroups(new)
+                controller.enqueueUsersOfGroup(new)
+            }
         },
+        DeleteFunc: controller.enqueueIncludingGroups,
     })
     
     version.SynchronisationSources().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-        AddFunc: controller.enqueueSyncSource,
+        AddFunc: func(obj interface{}) {
+            controller.enqueueSyncSource(obj)
+            controller.enqueueGroupsOfSyncSource(obj)
+        },
         UpdateFunc: func(old, new interface{}) {
+            if !needsReconcile(old, new) {
+                return
+            }
             controller.enqueueSyncSource(new)
+            if groupSubjectsChanged(old, new) {
+                controller.enqueueGroupsOfSyncSource(old)
+                controller.enqueueGroupsOfSyncSource(new)
+            }
+        },
+        DeleteFunc: controller.enqueueGroupsOfSyncSource,
+    })
+
+    version.AccessRequests().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+        AddFunc: controller.enqueueAccessRequest,
+        UpdateFunc: func(old, new interface{}) {
+            if needsReconcile(old, new) {
+                controller.enqueueAccessRequest(new)
+            }
+        },
+        // revokes the membership of a request that is deleted while it is active
+        DeleteFunc: controller.enqueueAccessRequest,
+    })
+
+    // Groups selecting their namespaces by pattern or labels follow the namespaces of the cluster
+    kubeInformerFactory.Core().V1().Namespaces().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+        AddFunc: controller.enqueueGroupsOfNamespace,
+        UpdateFunc: func(old, new interface{}) {
+            if namespaceLabelsChanged(old, new) {
+                controller.enqueueGroupsOfNamespace(old)
+                controller.enqueueGroupsOfNamespace(new)
+            }
         },
+        DeleteFunc: controller.enqueueGroupsOfNamespace,
     })
 
+    // The managed informer factory only watches objects carrying the perm8s labels, so that manual changes
+    // to any of them are reverted right away instead of waiting for the next resync of their owner
+    managedInformers := []cache.SharedIndexInformer{
+        managedInformerFactory.Rbac().V1().ClusterRoles().Informer(),
+        managedInformerFactory.Rbac().V1().ClusterRoleBindings().Informer(),
+        managedInformerFactory.Rbac().V1().RoleBindings().Informer(),
+        managedInformerFactory.Rbac().V1().Roles().Informer(),
+        managedInformerFactory.Core().V1().ServiceAccounts().Informer(),
+        managedInformerFactory.Core().V1().Secrets().Informer(),
+    }
+
+    for _, informer := range managedInformers {
+        informer.AddEventHandler(controller.managedObjectEventHandler())
+        controller.managedObjectsSynced = append(controller.managedObjectsSynced, informer.HasSynced)
+    }
+
+    Registry.MustRegister(newManagedBindingsCollector(
+        managedInformerFactory.Rbac().V1().RoleBindings().Lister(),
+        managedInformerFactory.Rbac().V1().ClusterRoleBindings().Lister(),
+    ))
+
     return controller
 }
 
 func (c *Controller) Run(ctx context.Context, workers int) error {
     defer utilruntime.HandleCrash()
-    defer c.userWorkqueue.ShutDown()
+    defer c.shutDownWorkqueues()
     logger := klog.FromContext(ctx)
 
     logger.Info("Controller Started, waiting for informer caches to sync")
 
-    if ok := cache.WaitForCacheSync(ctx.Done(), c.groupsSynced, c.usersSynced); !ok {
+    if ok := cache.WaitForCacheSync(ctx.Done(), c.informersSynced()...); !ok {
         return fmt.Errorf("failed to wait for caches to sync")
     }
 
-    logger.Info("Starting user workers", "count", workers)
-    for i := 0; i < workers; i++ {
-        go wait.UntilWithContext(ctx, c.runUserWorker, time.Second)
+    if c.options.LeaderElection.Enabled {
+        return c.runWithLeaderElection(ctx, workers)
+    }
+
+    c.runWorkers(ctx, workers)
+    return nil
+}
+
+// runWorkers starts the workers of all workqueues and blocks until the context is cancelled and every worker has stopped
+func (c *Controller) runWorkers(ctx context.Context, workers int) {
+    logger := klog.FromContext(ctx)
+    var wg sync.WaitGroup
+
+    startWorkers := func(worker func(context.Context)) {
+        for i := 0; i < workers; i++ {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                wait.UntilWithContext(ctx, worker, time.Second)
+            }()
+        }
     }
+
+    logger.Info("Starting user workers", "count", workers)
+    startWorkers(c.runUserWorker)
     
     logger.Info("Starting group workers", "count", workers)
-    for i := 0; i < workers; i++ {
-        go wait.UntilWithContext(ctx, c.runGroupWorker, time.Second)
-    }
+    startWorkers(c.runGroupWorker)
     
     logger.Info("Starting authentik workers", "count", workers)
-    for i := 0; i < workers; i++ {
-        go wait.UntilWithContext(ctx, c.runSyncSourceWorker, time.Second)
-    }
+    startWorkers(c.runSyncSourceWorker)
+
+    logger.Info("Starting access request workers", "count", workers)
+    startWorkers(c.runAccessRequestWorker)
 
     logger.Info("Started workers")
     <-ctx.Done()
     logger.Info("Shutting down workers")
 
-    return nil
+    // workers only return once their workqueue is shut down
+    c.shutDownWorkqueues()
+    wg.Wait()
+    logger.Info("Workers stopped")
+}
+
+func (c *Controller) shutDownWorkqueues() {
+    c.userWorkqueue.ShutDown()
+    c.groupWorkqueue.ShutDown()
+    c.syncSourceWorkqueue.ShutDown()
+    c.accessRequestWorkqueue.ShutDown()
 }
 
+// needsReconcile filters out updates that only touched the status subresource, as writing the status would
+// otherwise cause an endless reconciliation loop. Periodic resyncs keep the same resource version and always pass,
+// annotation changes pass as they are used to trigger actions.
+func needsReconcile(old, new interface{}) bool {
+    oldObj, ok := old.(v3.Object)
+    if !ok {
+        return true
+    }
+    newObj, ok := new.(v3.Object)
+    if !ok {
+        return true
+    }
+
+    return oldObj.GetResourceVersion() == newObj.GetResourceVersion() ||
+        oldObj.GetGeneration() != newObj.GetGeneration() ||
+        !oldObj.GetDeletionTimestamp().Equal(newObj.GetDeletionTimestamp()) ||
+        !maps.Equal(oldObj.GetAnnotations(), newObj.GetAnnotations())
+}
@@ -2,15 +2,20 @@ package controller
 
 import (
     "context"
+    stderrors "errors"
+    "fmt"
     v2 "k8s.io/api/core/v1"
     v4 "k8s.io/api/rbac/v1"
+    "k8s.io/apimachinery/pkg/api/equality"
     "k8s.io/apimachinery/pkg/api/errors"
     v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/labels"
     utilruntime "k8s.io/apimachinery/pkg/util/runtime"
     "k8s.io/client-go/tools/cache"
     "k8s.io/klog/v2"
     v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
-    "reflect"
+    "slices"
+    "time"
 )
 
 func (c *Controller) enqueueGroup(obj interface{}) {
@@ -22,6 +27,140 @@ func (c *Controller) enqueueGroup(obj interface{}) {
     }
 }
 
+// enqueueGroupsOfUser enqueues every group the given user is a member of, so that their member count is kept up to date
+func (c *Controller) enqueueGroupsOfUser(obj interface{}) {
+    if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+        obj = tombstone.Obj
+    }
+
+    user, ok := obj.(*v1alpha2.User)
+    if !ok {
+        return
+    }
+
+    for _, groupName := range referencedGroups(user) {
+        c.groupWorkqueue.Add(cache.ObjectName{Namespace: user.Namespace, Name: groupName})
+    }
+}
+
+// enqueueUsersOfGroup enqueues every user that is bound to the given group, directly or through an including group,
+// so that changes to the bindings of a group are applied to its members right away
+func (c *Controller) enqueueUsersOfGroup(obj interface{}) {
+    if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+        obj = tombstone.Obj
+    }
+
+    group, ok := obj.(*v1alpha2.Group)
+    if !ok {
+        return
+    }
+
+    users, err := c.userLister.Users(group.Namespace).List(labels.Everything())
+    if err != nil {
+        utilruntime.HandleError(err)
+        return
+    }
+
+    now := time.Now()
+    for _, user := range users {
+        memberships, _, _ := activeGroupMemberships(user, now)
+        if effectiveGroups, _ := c.expandGroups(user.Namespace, memberships); slices.Contains(effectiveGroups, group.Name) {
+            c.userWorkqueue.Add(cache.ObjectName{Namespace: user.Namespace, Name: user.Name})
+        }
+    }
+}
+
+// enqueueIncludingGroups enqueues every group that includes the given group, directly or transitively, as their
+// IncludedGroups and include problems depend on it
+func (c *Controller) enqueueIncludingGroups(obj interface{}) {
+    if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+        obj = tombstone.Obj
+    }
+
+    group, ok := obj.(*v1alpha2.Group)
+    if !ok {
+        return
+    }
+
+    groups, err := c.groupLister.Groups(group.Namespace).List(labels.Everything())
+    if err != nil {
+        utilruntime.HandleError(err)
+        return
+    }
+
+    for _, candidate := range groups {
+        if candidate.Name == group.Name {
+            continue
+        }
+
+        if included, _ := c.expandGroups(candidate.Namespace, []string{candidate.Name}); slices.Contains(included, group.Name) {
+            c.groupWorkqueue.Add(cache.ObjectName{Namespace: candidate.Namespace, Name: candidate.Name})
+        }
+    }
+}
+
+// bindingsChanged reports whether an update changed anything that decides which bindings the members of a group receive
+func bindingsChanged(old, new interface{}) bool {
+    oldGroup, ok := old.(*v1alpha2.Group)
+    if !ok {
+        return true
+    }
+
+    newGroup, ok := new.(*v1alpha2.Group)
+    if !ok {
+        return true
+    }
+
+    return !slices.Equal(oldGroup.Spec.Includes, newGroup.Spec.Includes) ||
+        !slices.Equal(oldGroup.Spec.Namespaces, newGroup.Spec.Namespaces) ||
+        !equality.Semantic.DeepEqual(oldGroup.Spec.NamespaceSelector, newGroup.Spec.NamespaceSelector) ||
+        !slices.Equal(oldGroup.Spec.ClusterRoleRefs, newGroup.Spec.ClusterRoleRefs) ||
+        !slices.Equal(oldGroup.Spec.RoleRefs, newGroup.Spec.RoleRefs) ||
+        !equality.Semantic.DeepEqual(oldGroup.Spec.NamespacePermissions, newGroup.Spec.NamespacePermissions) ||
+        oldGroup.Spec.ClusterGroup != newGroup.Spec.ClusterGroup ||
+        oldGroup.Spec.NamespacedRoles != newGroup.Spec.NamespacedRoles ||
+        (len(oldGroup.Spec.ClusterPermissions) > 0) != (len(newGroup.Spec.ClusterPermissions) > 0) ||
+        hasGeneratedClusterRole(oldGroup) != hasGeneratedClusterRole(newGroup)
+}
+
+// errClusterRoleNotOwned is returned by ensureGroupClusterRole for a ClusterRole of the desired name that was not
+// created for the group
+var errClusterRoleNotOwned = stderrors.New("ClusterRole exists but does not belong to the group")
+
+// clusterRoleConflict reports whether err is caused by a ClusterRole that does not belong to the group, in which case
+// it is recorded in the status so that the members are not bound to it
+func (c *Controller) clusterRoleConflict(group *v1alpha2.Group, status *v1alpha2.GroupStatus, name string, err error) bool {
+    if !stderrors.Is(err, errClusterRoleNotOwned) {
+        return false
+    }
+
+    c.recorder.Event(group, v2.EventTypeWarning, ReasonRoleConflict, err.Error())
+    status.ConflictingClusterRoles = append(status.ConflictingClusterRoles, name)
+    return true
+}
+
+// clusterRoleConflicts reports whether the ClusterRole of the given name belongs to someone else than the group
+func clusterRoleConflicts(group *v1alpha2.Group, name string) bool {
+    return slices.Contains(group.Status.ConflictingClusterRoles, name)
+}
+
+// conflictsChanged reports whether a status update changed which of the roles of the group belong to someone else, as
+// the members are not bound to them
+func conflictsChanged(old, new interface{}) bool {
+    oldGroup, ok := old.(*v1alpha2.Group)
+    if !ok {
+        return true
+    }
+
+    newGroup, ok := new.(*v1alpha2.Group)
+    if !ok {
+        return true
+    }
+
+    return !slices.Equal(oldGroup.Status.ConflictingRoles, newGroup.Status.ConflictingRoles) ||
+        !slices.Equal(oldGroup.Status.ConflictingClusterRoles, newGroup.Status.ConflictingClusterRoles)
+}
+
 func (c *Controller) runGroupWorker(ctx context.Context) {
     for c.processNextGroupWorkItem(ctx) {
     }
@@ -36,8 +175,12 @@ func (c *Controller) processNextGroupWorkItem(ctx context.Context) bool {
     }
 
     defer c.groupWorkqueue.Done(objRef)
+    defer c.heartbeats.begin(kindGroup)()
 
+    start := time.Now()
     err := c.syncGroupHandler(ctx, objRef.(cache.ObjectName))
+    observeReconcile(kindGroup, start, err)
+
     if err == nil {
         c.groupWorkqueue.Forget(objRef)
         logger.Info("Successfully synced", "objectName", objRef)
@@ -64,51 +207,432 @@ func (c *Controller) syncGroupHandler(ctx context.Context, objectRef cache.Objec
         return err
     }
 
-    clusterRole, err := c.kubeclientset.RbacV1().ClusterRoles().Get(ctx, group.Name, v3.GetOptions{})
+    if group.DeletionTimestamp != nil {
+        return c.finalizeGroup(ctx, group)
+    }
+
+    if !slices.Contains(group.Finalizers, GroupFinalizer) {
+        logger.Info("Adding cleanup finalizer to group", "group", group.Name)
+        group = group.DeepCopy()
+        group.Finalizers = append(group.Finalizers, GroupFinalizer)
+
+        group, err = c.clientSet.Perm8sV1alpha1().Groups(group.Namespace).Update(ctx, group, v3.UpdateOptions{FieldManager: FieldManager})
+        if err != nil {
+            return err
+        }
+    }
+
+    includedGroups, _ := c.expandGroups(group.Namespace, []string{group.Name})
+
+    status := v1alpha2.GroupStatus{
+        ObservedGeneration: group.Generation,
+        Conditions:         slices.Clone(group.Status.Conditions),
+        MemberCount:        c.countGroupMembers(group),
+        IncludedGroups:     includedGroups[1:],
+    }
+
+    err = c.reconcileGroup(ctx, group, &status)
+    if err == nil {
+        err = c.reconcileClusterPermissions(ctx, group, &status)
+    }
+    if err == nil {
+        err = c.reconcileNamespacePermissions(ctx, group, &status)
+    }
+    if err == nil {
+        err = c.reconcileGroupRoles(ctx, group, &status)
+    }
+    // conflicts that were found before keep their bindings from being created until a reconcile completes
+    if err != nil {
+        for _, conflict := range group.Status.ConflictingRoles {
+            if !slices.Contains(status.ConflictingRoles, conflict) {
+                status.ConflictingRoles = append(status.ConflictingRoles, conflict)
+            }
+        }
+        for _, conflict := range group.Status.ConflictingClusterRoles {
+            if !slices.Contains(status.ConflictingClusterRoles, conflict) {
+                status.ConflictingClusterRoles = append(status.ConflictingClusterRoles, conflict)
+            }
+        }
+    }
+    if err == nil {
+        // the subject bindings have to skip the conflicts that were just found, not those of the last status
+        withConflicts := group.DeepCopy()
+        withConflicts.Status.ConflictingRoles = status.ConflictingRoles
+        withConflicts.Status.ConflictingClusterRoles = status.ConflictingClusterRoles
+        err = c.reconcileGroupSubjects(ctx, withConflicts, &status)
+    }
+
+    problems, reason := c.includeProblems(group), ReasonInvalidIncludes
+    if roleProblems, roleErr := c.roleRefProblems(ctx, group); roleErr != nil {
+        if err == nil {
+            err = roleErr
+        }
+    } else if len(roleProblems) > 0 {
+        if len(problems) == 0 {
+            reason = ReasonRoleNotFound
+        }
+        problems = append(problems, roleProblems...)
+    }
+    if len(status.ConflictingRoles) > 0 || len(status.ConflictingClusterRoles) > 0 {
+        if len(problems) == 0 {
+            reason = ReasonRoleConflict
+        }
+        for _, conflict := range status.ConflictingClusterRoles {
+            problems = append(problems, fmt.Sprintf("ClusterRole %v already exists without belonging to the group", conflict))
+        }
+        for _, conflict := range status.ConflictingRoles {
+            problems = append(problems, fmt.Sprintf("Role %v already exists without belonging to the group", conflict))
+        }
+    }
+
+    setReadyCondition(&status.Conditions, group.Generation, err)
+    setDegradedCondition(&status.Conditions, group.Generation, reason, problems)
+
+    if statusErr := c.updateGroupStatus(ctx, group, status); statusErr != nil {
+        logger.Error(statusErr, "Error while updating Group status", "group", group.Name)
+        if err == nil {
+            return statusErr
+        }
+    }
+
+    return err
+}
+
+// reconcileGroup makes sure the ClusterRole rendered from the given group exists and matches its permissions
+func (c *Controller) reconcileGroup(ctx context.Context, group *v1alpha2.Group, status *v1alpha2.GroupStatus) error {
+    logger := klog.LoggerWithValues(klog.FromContext(ctx), "group", group.Name)
+
+    if !group.Spec.ClusterGroup {
+        namespaces, err := c.groupNamespaces(group)
+        if err != nil {
+            return err
+        }
+        status.Namespaces = namespaces
+    }
+
+    if !hasGeneratedClusterRole(group) {
+        if err := c.removeGroupClusterRole(ctx, group, PermissionsClusterRoleName(group)); err != nil {
+            return err
+        }
+        return c.removeGroupClusterRole(ctx, group, group.Name)
+    }
+
+    // the rules of an aggregated ClusterRole are owned by the aggregation controller, so the permissions of the group
+    // are moved into a separate ClusterRole that is aggregated into it
+    if isAggregated(group) && len(group.Spec.Permissions) > 0 {
+        if _, err := c.ensureGroupClusterRole(ctx, group, c.PermissionsClusterRoleFromGroup(group)); err != nil && !c.clusterRoleConflict(group, status, PermissionsClusterRoleName(group), err) {
+            return err
+        }
+    } else if err := c.removeGroupClusterRole(ctx, group, PermissionsClusterRoleName(group)); err != nil {
+        return err
+    }
+
+    clusterRole, err := c.ensureGroupClusterRole(ctx, group, c.ClusterRoleFromGroup(group))
+    if c.clusterRoleConflict(group, status, group.Name, err) {
+        return nil
+    }
+    if err != nil {
+        logger.Error(err, "Error while syncing ClusterRole", "clusterRoleName", group.Name)
+        return err
+    }
+
+    status.ClusterRoleName = clusterRole.Name
+
+    c.recorder.Event(group, v2.EventTypeNormal, SuccessSynced, MessageGroupSynced)
+    return nil
+}
+
+// ensureGroupClusterRole creates the desired ClusterRole of the group or restores it if it drifted. The rules of aggregated
+// ClusterRoles are filled in by the aggregation controller and therefore not compared.
+func (c *Controller) ensureGroupClusterRole(ctx context.Context, group *v1alpha2.Group, desired *v4.ClusterRole) (*v4.ClusterRole, error) {
+    logger := klog.LoggerWithValues(klog.FromContext(ctx), "group", group.Name, "clusterRoleName", desired.Name)
+
+    clusterRole, err := c.kubeclientset.RbacV1().ClusterRoles().Get(ctx, desired.Name, v3.GetOptions{})
 
     if errors.IsNotFound(err) {
-        logger.Info("Cluster Role does not exist yet, creating new", "groupName", group.Name)
-        clusterRole, err = c.kubeclientset.RbacV1().ClusterRoles().Create(ctx, c.ClusterRoleFromGroup(group), v3.CreateOptions{})
+        logger.Info("Cluster Role does not exist yet, creating new")
+        clusterRole, err = c.kubeclientset.RbacV1().ClusterRoles().Create(ctx, desired, v3.CreateOptions{})
 
         if err != nil {
-            logger.Error(err, "Error while creating ClusterRole", "group", group.Name)
-            return nil
+            logger.Error(err, "Error while creating ClusterRole")
+            return nil, err
         }
 
         logger.Info("ClusterRole created successfully")
 
-        c.recorder.Event(group, v2.EventTypeNormal, SuccessCreated, "ClusterRole created successfully")
+        c.recorder.Event(group, v2.EventTypeNormal, SuccessCreated, "ClusterRole "+desired.Name+" created successfully")
+        return clusterRole, nil
     }
-    
+
     if err != nil {
-        return err
+        return nil, err
     }
-    
-    desiredClusterRoleState := c.ClusterRoleFromGroup(group)
-    
-    if !reflect.DeepEqual(clusterRole.Rules,  desiredClusterRoleState.Rules) {
-        logger.Info("Cluster role is out of sync, resyncing", "clusterRoleName", clusterRole.Name)
-        _, err = c.kubeclientset.RbacV1().ClusterRoles().Update(ctx, desiredClusterRoleState, v3.UpdateOptions{})
-        
+
+    // the names of ClusterRoles are not scoped to the namespace of the group, so they may belong to a built-in role,
+    // to a group of the same name in another namespace or to anyone else. ClusterRoles of earlier versions only carry
+    // the owner reference of the group and receive its labels below
+    owned := clusterRole.Labels[LabelGroup] == group.Name && clusterRole.Labels[LabelNamespace] == group.Namespace
+    if !owned && !v3.IsControlledBy(clusterRole, group) {
+        return nil, fmt.Errorf("%w: %v", errClusterRoleNotOwned, desired.Name)
+    }
+
+    desiredLabels, labelsChanged := mergeMetadata(clusterRole.Labels, desired.Labels)
+    desiredAnnotations, annotationsChanged := mergeMetadata(clusterRole.Annotations, desired.Annotations)
+
+    // aggregate-to labels that the group no longer asks for would keep granting its rules to the built-in roles
+    for label := range desiredLabels {
+        if slices.Contains(aggregateToLabels, label) {
+            if _, ok := desired.Labels[label]; !ok {
+                delete(desiredLabels, label)
+                labelsChanged = true
+            }
+        }
+    }
+
+    rulesChanged := desired.AggregationRule == nil && !equality.Semantic.DeepEqual(clusterRole.Rules, desired.Rules)
+
+    if labelsChanged || annotationsChanged || rulesChanged ||
+        !equality.Semantic.DeepEqual(clusterRole.AggregationRule, desired.AggregationRule) {
+        logger.Info("Cluster role is out of sync, resyncing")
+        updatedClusterRole := clusterRole.DeepCopy()
+        updatedClusterRole.Labels = desiredLabels
+        updatedClusterRole.Annotations = desiredAnnotations
+        updatedClusterRole.AggregationRule = desired.AggregationRule
+        if desired.AggregationRule == nil {
+            updatedClusterRole.Rules = desired.Rules
+        }
+
+        clusterRole, err = c.kubeclientset.RbacV1().ClusterRoles().Update(ctx, updatedClusterRole, v3.UpdateOptions{FieldManager: FieldManager})
+
         if err != nil {
-            logger.Error(err, "Error while syncing ClusterRole", "clusterRoleName", clusterRole.Name)
+            return nil, err
+        }
+
+        c.recorder.Event(group, v2.EventTypeNormal, SuccessSynced, "ClusterRole "+desired.Name+" synchronised successfully")
+    }
+
+    return clusterRole, nil
+}
+
+// finalizeGroup removes the ClusterRole and Roles of a deleted group as well as every RoleBinding and ClusterRoleBinding
+// that binds a user to it, enqueues the affected users and finally releases the finalizer
+func (c *Controller) finalizeGroup(ctx context.Context, group *v1alpha2.Group) error {
+    logger := klog.LoggerWithValues(klog.FromContext(ctx), "group", group.Name)
+
+    if !slices.Contains(group.Finalizers, GroupFinalizer) {
+        return nil
+    }
+
+    logger.Info("Group is being deleted, cleaning up managed resources")
+
+    selector := fmt.Sprintf("%v=%v,%v=%v", LabelGroup, group.Name, LabelNamespace, group.Namespace)
+    affectedUsers := map[string]bool{}
+
+    roleBindings, err := c.kubeclientset.RbacV1().RoleBindings("").List(ctx, v3.ListOptions{LabelSelector: selector})
+    if err != nil {
+        return err
+    }
+
+    for _, roleBinding := range roleBindings.Items {
+        logger.Info("Deleting RoleBinding of deleted group", "namespace", roleBinding.Namespace, "roleBinding", roleBinding.Name)
+        err = c.kubeclientset.RbacV1().RoleBindings(roleBinding.Namespace).Delete(ctx, roleBinding.Name, v3.DeleteOptions{})
+        if err != nil && !errors.IsNotFound(err) {
+            logger.Error(err, "Error while deleting RoleBinding of deleted group", "namespace", roleBinding.Namespace, "roleBinding", roleBinding.Name)
             return err
         }
-        
-        c.recorder.Event(group, v2.EventTypeNormal, SuccessSynced, "ClusterRole synchronised successfully")
+
+        affectedUsers[roleBinding.Labels[LabelUser]] = true
     }
 
-    // todo update cluster role where necessary
+    roles, err := c.kubeclientset.RbacV1().Roles("").List(ctx, v3.ListOptions{LabelSelector: selector})
+    if err != nil {
+        return err
+    }
 
-    c.recorder.Event(group, v2.EventTypeNormal, SuccessSynced, MessageGroupSynced)
+    for _, role := range roles.Items {
+        logger.Info("Deleting Role of deleted group", "namespace", role.Namespace, "role", role.Name)
+        err = c.kubeclientset.RbacV1().Roles(role.Namespace).Delete(ctx, role.Name, v3.DeleteOptions{})
+        if err != nil && !errors.IsNotFound(err) {
+            logger.Error(err, "Error while deleting Role of deleted group", "namespace", role.Namespace, "role", role.Name)
+            return err
+        }
+    }
+
+    clusterRoleBindings, err := c.kubeclientset.RbacV1().ClusterRoleBindings().List(ctx, v3.ListOptions{LabelSelector: selector})
+    if err != nil {
+        return err
+    }
+
+    for _, clusterRoleBinding := range clusterRoleBindings.Items {
+        logger.Info("Deleting ClusterRoleBinding of deleted group", "clusterRoleBinding", clusterRoleBinding.Name)
+        err = c.kubeclientset.RbacV1().ClusterRoleBindings().Delete(ctx, clusterRoleBinding.Name, v3.DeleteOptions{})
+        if err != nil && !errors.IsNotFound(err) {
+            logger.Error(err, "Error while deleting ClusterRoleBinding of deleted group", "clusterRoleBinding", clusterRoleBinding.Name)
+            return err
+        }
+
+        affectedUsers[clusterRoleBinding.Labels[LabelUser]] = true
+    }
+
+    clusterRoleNames := []string{group.Name, PermissionsClusterRoleName(group), ClusterPermissionsClusterRoleName(group)}
+    for _, permissions := range group.Spec.NamespacePermissions {
+        clusterRoleNames = append(clusterRoleNames, NamespacePermissionsClusterRoleName(group, permissions))
+    }
+
+    for _, clusterRoleName := range clusterRoleNames {
+        if err = c.removeGroupClusterRole(ctx, group, clusterRoleName); err != nil {
+            logger.Error(err, "Error while deleting ClusterRole of deleted group", "clusterRoleName", clusterRoleName)
+            return err
+        }
+    }
+
+    users, err := c.userLister.Users(group.Namespace).List(labels.Everything())
+    if err != nil {
+        return err
+    }
+
+    for _, user := range users {
+        if slices.Contains(referencedGroups(user), group.Name) {
+            affectedUsers[user.Name] = true
+        }
+    }
+
+    for userName := range affectedUsers {
+        if userName != "" {
+            c.userWorkqueue.Add(cache.ObjectName{Namespace: group.Namespace, Name: userName})
+        }
+    }
+
+    group = group.DeepCopy()
+    group.Finalizers = slices.DeleteFunc(group.Finalizers, func(finalizer string) bool {
+        return finalizer == GroupFinalizer
+    })
+
+    _, err = c.clientSet.Perm8sV1alpha1().Groups(group.Namespace).Update(ctx, group, v3.UpdateOptions{FieldManager: FieldManager})
+    if err != nil && !errors.IsNotFound(err) {
+        return err
+    }
+
+    logger.Info("Group cleanup finished", "affectedUsers", len(affectedUsers))
+    return nil
+}
+
+// removeGroupClusterRole deletes a ClusterRole generated for the group that is no longer needed.
+// ClusterRoles that are not labelled as belonging to the group are left alone.
+func (c *Controller) removeGroupClusterRole(ctx context.Context, group *v1alpha2.Group, name string) error {
+    clusterRole, err := c.kubeclientset.RbacV1().ClusterRoles().Get(ctx, name, v3.GetOptions{})
+    if errors.IsNotFound(err) {
+        return nil
+    }
+
+    if err != nil {
+        return err
+    }
+
+    if clusterRole.Labels[LabelGroup] != group.Name || clusterRole.Labels[LabelNamespace] != group.Namespace {
+        return nil
+    }
+
+    klog.FromContext(ctx).Info("ClusterRole is no longer needed by the group, deleting", "group", group.Name, "clusterRoleName", clusterRole.Name)
+    err = c.kubeclientset.RbacV1().ClusterRoles().Delete(ctx, clusterRole.Name, v3.DeleteOptions{})
+    if err != nil && !errors.IsNotFound(err) {
+        return err
+    }
+
+    c.recorder.Event(group, v2.EventTypeNormal, SuccessSynced, "Deleted ClusterRole "+clusterRole.Name+" as the group no longer needs it")
     return nil
 }
 
+// hasGeneratedClusterRole reports whether a ClusterRole is generated for the group and bound to its members
+func hasGeneratedClusterRole(group *v1alpha2.Group) bool {
+    return (len(group.Spec.Permissions) > 0 && !hasGeneratedRole(group)) || isAggregated(group)
+}
+
+// isAggregated reports whether the generated ClusterRole of the group aggregates the rules of other ClusterRoles
+func isAggregated(group *v1alpha2.Group) bool {
+    return len(group.Spec.AggregationLabels) > 0
+}
+
+// roleRefProblems returns the roles referenced by the group that do not exist. Members are bound to them regardless,
+// the bindings take effect once the roles are created
+func (c *Controller) roleRefProblems(ctx context.Context, group *v1alpha2.Group) ([]string, error) {
+    var problems []string
+
+    clusterRoleNames := slices.Clone(group.Spec.ClusterRoleRefs)
+    for _, permissions := range group.Spec.NamespacePermissions {
+        if permissions.ClusterRoleRef != "" && !slices.Contains(clusterRoleNames, permissions.ClusterRoleRef) {
+            clusterRoleNames = append(clusterRoleNames, permissions.ClusterRoleRef)
+        }
+    }
+
+    for _, clusterRoleName := range clusterRoleNames {
+        _, err := c.kubeclientset.RbacV1().ClusterRoles().Get(ctx, clusterRoleName, v3.GetOptions{})
+        if errors.IsNotFound(err) {
+            problems = append(problems, fmt.Sprintf("ClusterRole %v does not exist", clusterRoleName))
+        } else if err != nil {
+            return nil, err
+        }
+    }
+
+    for _, roleRef := range group.Spec.RoleRefs {
+        _, err := c.kubeclientset.RbacV1().Roles(roleRef.Namespace).Get(ctx, roleRef.Name, v3.GetOptions{})
+        if errors.IsNotFound(err) {
+            problems = append(problems, fmt.Sprintf("Role %v/%v does not exist", roleRef.Namespace, roleRef.Name))
+        } else if err != nil {
+            return nil, err
+        }
+    }
+
+    return problems, nil
+}
+
 func (c *Controller) ClusterRoleFromGroup(group *v1alpha2.Group) *v4.ClusterRole {
+    clusterRoleLabels := groupLabels(group)
+    for _, role := range group.Spec.AggregateTo {
+        clusterRoleLabels[aggregateToLabelPrefix+string(role)] = "true"
+    }
+
+    clusterRole := &v4.ClusterRole{
+        ObjectMeta: v3.ObjectMeta{
+            Name:   group.Name,
+            Labels: clusterRoleLabels,
+            OwnerReferences: []v3.OwnerReference{
+                *v3.NewControllerRef(group, v1alpha2.SchemeGroupVersion.WithKind("Group")),
+            },
+        },
+
+        Rules: group.Spec.Permissions,
+    }
+
+    if isAggregated(group) {
+        clusterRole.Rules = nil
+        clusterRole.AggregationRule = &v4.AggregationRule{}
+        for _, matchLabels := range group.Spec.AggregationLabels {
+            clusterRole.AggregationRule.ClusterRoleSelectors = append(clusterRole.AggregationRule.ClusterRoleSelectors, v3.LabelSelector{MatchLabels: matchLabels})
+        }
+
+        if len(group.Spec.Permissions) > 0 {
+            clusterRole.AggregationRule.ClusterRoleSelectors = append(clusterRole.AggregationRule.ClusterRoleSelectors, v3.LabelSelector{
+                MatchLabels: map[string]string{
+                    LabelAggregateToGroup: group.Name,
+                    LabelNamespace:        group.Namespace,
+                },
+            })
+        }
+    }
+
+    return clusterRole
+}
+
+// PermissionsClusterRoleFromGroup holds the permissions of an aggregated group, which are aggregated into its ClusterRole
+func (c *Controller) PermissionsClusterRoleFromGroup(group *v1alpha2.Group) *v4.ClusterRole {
+    clusterRoleLabels := groupLabels(group)
+    clusterRoleLabels[LabelAggregateToGroup] = group.Name
+
     return &v4.ClusterRole{
         ObjectMeta: v3.ObjectMeta{
-            Name:      group.Name,
-            Namespace: group.Namespace,
+            Name:   PermissionsClusterRoleName(group),
+            Labels: clusterRoleLabels,
             OwnerReferences: []v3.OwnerReference{
                 *v3.NewControllerRef(group, v1alpha2.SchemeGroupVersion.WithKind("Group")),
             },
@@ -117,3 +641,27 @@ func (c *Controller) ClusterRoleFromGroup(group *v1alpha2.Group) *v4.ClusterRole
         Rules: group.Spec.Permissions,
     }
 }
+
+func PermissionsClusterRoleName(group *v1alpha2.Group) string {
+    return group.Name + ":permissions"
+}
+
+// countGroupMembers returns the number of users in the namespace of the group that are currently members of it and are not suspended
+func (c *Controller) countGroupMembers(group *v1alpha2.Group) int {
+    users, err := c.userLister.Users(group.Namespace).List(labels.Everything())
+    if err != nil {
+        utilruntime.HandleError(err)
+        return group.Status.MemberCount
+    }
+
+    count := 0
+    now := time.Now()
+    for _, user := range users {
+        memberships, _, _ := activeGroupMemberships(user, now)
+        if !user.Spec.Suspended && slices.Contains(memberships, group.Name) {
+            count++
+        }
+    }
+
+    return count
+}
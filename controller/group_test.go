@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v4 "k8s.io/api/rbac/v1"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+func TestGroupClusterRolesSkipUnownedClusterRoles(t *testing.T) {
+	rules := []v4.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}}
+	builtinRules := []v4.PolicyRule{{Verbs: []string{"get", "list", "watch"}, APIGroups: []string{"*"}, Resources: []string{"*"}}}
+
+	group := &v1alpha2.Group{
+		ObjectMeta: v3.ObjectMeta{Name: "view", Namespace: "team", UID: "group-uid"},
+		Spec: v1alpha2.GroupSpec{
+			ClusterGroup:       true,
+			Permissions:        rules,
+			ClusterPermissions: rules,
+		},
+	}
+
+	// the built-in ClusterRole of the same name and the cluster permissions of a group of the same name in another namespace
+	builtin := &v4.ClusterRole{ObjectMeta: v3.ObjectMeta{Name: "view"}, Rules: builtinRules}
+	otherGroup := &v4.ClusterRole{
+		ObjectMeta: v3.ObjectMeta{Name: "view:cluster", Labels: map[string]string{LabelGroup: "view", LabelNamespace: "other"}},
+		Rules:      builtinRules,
+	}
+
+	kubeClient := fake.NewSimpleClientset(builtin, otherGroup)
+	c := &Controller{kubeclientset: kubeClient, recorder: record.NewFakeRecorder(10)}
+
+	status := v1alpha2.GroupStatus{}
+	if err := c.reconcileGroup(context.Background(), group, &status); err != nil {
+		t.Fatalf("reconcileGroup() error = %v", err)
+	}
+	if err := c.reconcileClusterPermissions(context.Background(), group, &status); err != nil {
+		t.Fatalf("reconcileClusterPermissions() error = %v", err)
+	}
+
+	if want := []string{"view", "view:cluster"}; !reflect.DeepEqual(status.ConflictingClusterRoles, want) {
+		t.Errorf("ConflictingClusterRoles = %v, want %v", status.ConflictingClusterRoles, want)
+	}
+	if status.ClusterRoleName != "" || status.ClusterPermissionsRoleName != "" {
+		t.Errorf("status references ClusterRoles that do not belong to the group: %+v", status)
+	}
+
+	for _, name := range []string{"view", "view:cluster"} {
+		clusterRole, err := kubeClient.RbacV1().ClusterRoles().Get(context.Background(), name, v3.GetOptions{})
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if !reflect.DeepEqual(clusterRole.Rules, builtinRules) || clusterRole.Labels[LabelNamespace] == group.Namespace {
+			t.Errorf("ClusterRole %v that does not belong to the group was modified: %+v", name, clusterRole)
+		}
+	}
+
+	group.Status = status
+	clusterRoleBindings, _, err := c.groupBindings(group, bindingTemplate{name: "jane-membership-view"})
+	if err != nil {
+		t.Fatalf("groupBindings() error = %v", err)
+	}
+	if len(clusterRoleBindings) > 0 {
+		t.Errorf("ClusterRoleBindings created for ClusterRoles that do not belong to the group: %v", clusterRoleBindings[0].RoleRef)
+	}
+}
+
+func TestGroupClusterRoleOfEarlierVersionIsAdopted(t *testing.T) {
+	rules := []v4.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}}
+
+	group := &v1alpha2.Group{
+		ObjectMeta: v3.ObjectMeta{Name: "developers", Namespace: "team", UID: "group-uid"},
+		Spec:       v1alpha2.GroupSpec{ClusterGroup: true, Permissions: rules},
+	}
+
+	// earlier versions only set the owner reference of the group
+	existing := &v4.ClusterRole{ObjectMeta: v3.ObjectMeta{
+		Name:            "developers",
+		OwnerReferences: []v3.OwnerReference{*v3.NewControllerRef(group, v1alpha2.SchemeGroupVersion.WithKind("Group"))},
+	}}
+
+	kubeClient := fake.NewSimpleClientset(existing)
+	c := &Controller{kubeclientset: kubeClient, recorder: record.NewFakeRecorder(10)}
+
+	status := v1alpha2.GroupStatus{}
+	if err := c.reconcileGroup(context.Background(), group, &status); err != nil {
+		t.Fatalf("reconcileGroup() error = %v", err)
+	}
+
+	if status.ClusterRoleName != "developers" || len(status.ConflictingClusterRoles) > 0 {
+		t.Errorf("status = %+v, want the ClusterRole to be adopted", status)
+	}
+
+	clusterRole, err := kubeClient.RbacV1().ClusterRoles().Get(context.Background(), "developers", v3.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(clusterRole.Rules, rules) || clusterRole.Labels[LabelGroup] != group.Name {
+		t.Errorf("ClusterRole was not updated: %+v", clusterRole)
+	}
+}
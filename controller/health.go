@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// defaultWorkerStuckTimeout is used when Options.WorkerStuckTimeout is not set
+const defaultWorkerStuckTimeout = 10 * time.Minute
+
+// workerHeartbeats tracks the items that are currently processed by the workers, so that a worker that never
+// finishes its item can be detected. Idle workers block on their workqueue and are always considered healthy.
+type workerHeartbeats struct {
+	mutex  sync.Mutex
+	nextID atomic.Uint64
+	busy   map[uint64]workerBeat
+}
+
+type workerBeat struct {
+	kind  string
+	since time.Time
+}
+
+// begin records that a worker of the given kind started processing an item and returns the function marking it as done
+func (h *workerHeartbeats) begin(kind string) func() {
+	id := h.nextID.Add(1)
+
+	h.mutex.Lock()
+	if h.busy == nil {
+		h.busy = map[uint64]workerBeat{}
+	}
+	h.busy[id] = workerBeat{kind: kind, since: time.Now()}
+	h.mutex.Unlock()
+
+	return func() {
+		h.mutex.Lock()
+		delete(h.busy, id)
+		h.mutex.Unlock()
+	}
+}
+
+// stuck returns the kinds of all workers that have been processing the same item for longer than timeout
+func (h *workerHeartbeats) stuck(timeout time.Duration) []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var kinds []string
+	for _, beat := range h.busy {
+		if time.Since(beat.since) > timeout && !slices.Contains(kinds, beat.kind) {
+			kinds = append(kinds, beat.kind)
+		}
+	}
+
+	slices.Sort(kinds)
+	return kinds
+}
+
+// informersSynced returns the sync functions of all informers the controller reads from
+func (c *Controller) informersSynced() []cache.InformerSynced {
+	return append([]cache.InformerSynced{c.usersSynced, c.groupsSynced, c.syncSourcesSynced, c.accessRequestsSynced, c.namespacesSynced}, c.managedObjectsSynced...)
+}
+
+// checkReadiness fails until the caches of all informers have synced
+func (c *Controller) checkReadiness() error {
+	for _, synced := range c.informersSynced() {
+		if !synced() {
+			return fmt.Errorf("informer caches have not synced yet")
+		}
+	}
+
+	return nil
+}
+
+// checkLiveness fails if any worker has been stuck on a single item for longer than the configured timeout
+func (c *Controller) checkLiveness() error {
+	timeout := c.options.WorkerStuckTimeout
+	if timeout <= 0 {
+		timeout = defaultWorkerStuckTimeout
+	}
+
+	if kinds := c.heartbeats.stuck(timeout); len(kinds) > 0 {
+		return fmt.Errorf("workers for %v have not finished their item within %v", strings.Join(kinds, ", "), timeout)
+	}
+
+	return nil
+}
+
+// checkSyncSources returns the reachability of every SynchronisationSource, keyed by namespace/name, as reported by
+// the SourceReachable condition of its last synchronisation. Sources that have not been synchronised yet are omitted.
+func (c *Controller) checkSyncSources() (map[string]error, error) {
+	sources, err := c.syncSourceLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[string]error{}
+	for _, source := range sources {
+		condition := meta.FindStatusCondition(source.Status.Conditions, v1alpha2.ConditionSourceReachable)
+		if condition == nil {
+			continue
+		}
+
+		var result error
+		if condition.Status != v3.ConditionTrue {
+			result = fmt.Errorf("%v", condition.Message)
+		}
+		results[source.Namespace+"/"+source.Name] = result
+	}
+
+	return results, nil
+}
+
+// HealthHandler serves the probes of the controller:
+//   - /healthz fails when a worker is stuck
+//   - /readyz fails until all informer caches have synced
+//   - /readyz/syncsources reports the reachability of every SynchronisationSource. It is not part of /readyz,
+//     as an unreachable upstream source is no reason to restart or unschedule the controller.
+func (c *Controller) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", probeHandler("workers", c.checkLiveness))
+	mux.HandleFunc("/readyz", probeHandler("informers", c.checkReadiness))
+	mux.HandleFunc("/readyz/syncsources", func(w http.ResponseWriter, r *http.Request) {
+		results, err := c.checkSyncSources()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		names := make([]string, 0, len(results))
+		for name := range results {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		checks := make([]probeResult, 0, len(names))
+		for _, name := range names {
+			checks = append(checks, probeResult{name: name, err: results[name]})
+		}
+
+		writeProbeResults(w, checks)
+	})
+	return mux
+}
+
+type probeResult struct {
+	name string
+	err  error
+}
+
+func probeHandler(name string, check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeProbeResults(w, []probeResult{{name: name, err: check()}})
+	}
+}
+
+// writeProbeResults writes one line per check in the format of the Kubernetes API server probes
+// and responds with 503 if any of them failed
+func writeProbeResults(w http.ResponseWriter, checks []probeResult) {
+	var body strings.Builder
+	failed := false
+
+	for _, check := range checks {
+		if check.err != nil {
+			failed = true
+			fmt.Fprintf(&body, "[-]%v failed: %v\n", check.name, check.err)
+		} else {
+			fmt.Fprintf(&body, "[+]%v ok\n", check.name)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if failed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		body.WriteString("check failed\n")
+	} else {
+		body.WriteString("ok\n")
+	}
+
+	_, _ = w.Write([]byte(body.String()))
+}
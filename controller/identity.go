@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/rbac/v1"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// reservedSubjectPrefix is the prefix of the users and groups the API server assigns itself, like system:authenticated.
+// External identities are never bound to them, as that would grant the roles to principals that are not the user
+const reservedSubjectPrefix = "system:"
+
+// IsReservedSubject reports whether the name of an external user or group is reserved by the API server once the
+// prefix is applied
+func IsReservedSubject(prefix string, name string) bool {
+	return strings.HasPrefix(prefix+name, reservedSubjectPrefix)
+}
+
+// hasServiceAccount reports whether a ServiceAccount with its token and kubeconfig is created for the user
+func hasServiceAccount(user *v1alpha2.User) bool {
+	return user.Spec.ServiceAccount == nil || *user.Spec.ServiceAccount
+}
+
+// userSubjects are the subjects of every binding of the user, which are its ServiceAccount and its external identity
+func (c *Controller) userSubjects(user *v1alpha2.User) []v1.Subject {
+	var subjects []v1.Subject
+
+	if hasServiceAccount(user) {
+		subjects = append(subjects, v1.Subject{
+			Name:      user.Name,
+			Kind:      v1.ServiceAccountKind,
+			Namespace: user.Namespace,
+		})
+	}
+
+	if identity := user.Spec.Identity; identity != nil {
+		if username := c.usernamePrefix(identity) + identity.Username; identity.Username != "" && !IsReservedSubject(c.usernamePrefix(identity), identity.Username) {
+			subjects = append(subjects, v1.Subject{
+				Name:     username,
+				Kind:     v1.UserKind,
+				APIGroup: v1.GroupName,
+			})
+		}
+
+		for _, group := range identity.Groups {
+			if IsReservedSubject(c.groupsPrefix(identity), group) {
+				continue
+			}
+
+			subjects = append(subjects, v1.Subject{
+				Name:     c.groupsPrefix(identity) + group,
+				Kind:     v1.GroupKind,
+				APIGroup: v1.GroupName,
+			})
+		}
+	}
+
+	return subjects
+}
+
+func (c *Controller) usernamePrefix(identity *v1alpha2.ExternalIdentity) string {
+	if identity.UsernamePrefix != nil {
+		return *identity.UsernamePrefix
+	}
+
+	return c.options.OIDCUsernamePrefix
+}
+
+func (c *Controller) groupsPrefix(identity *v1alpha2.ExternalIdentity) string {
+	if identity.GroupsPrefix != nil {
+		return *identity.GroupsPrefix
+	}
+
+	return c.options.OIDCGroupsPrefix
+}
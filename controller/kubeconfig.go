@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	v2 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// KubeconfigSecretKey is the key of the kubeconfig in the generated Secret of every user
+const KubeconfigSecretKey = "kubeconfig"
+
+// reconcileKubeconfig renders a kubeconfig from the given token and CA bundle into the kubeconfig Secret of the user.
+// The Secret is rewritten whenever the token, the CA bundle or the default namespace of the user change.
+func (c *Controller) reconcileKubeconfig(ctx context.Context, user *v1alpha2.User, token []byte, caData []byte, status *v1alpha2.UserStatus) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "user", user.Name)
+
+	if len(token) == 0 {
+		logger.Info("Token for user has not been issued yet, kubeconfig will be generated once it exists")
+		return nil
+	}
+
+	desiredSecret, err := c.KubeconfigSecretForUser(user, token, caData, defaultNamespace(status))
+	if err != nil {
+		return err
+	}
+
+	secret, err := c.apiClient.Secrets(user.Namespace).Get(ctx, desiredSecret.Name, v3.GetOptions{})
+	if errors.IsNotFound(err) {
+		logger.Info("Kubeconfig secret does not exist yet for user, creating", "secret", desiredSecret.Name)
+		_, err = c.apiClient.Secrets(user.Namespace).Create(ctx, desiredSecret, v3.CreateOptions{FieldManager: FieldManager})
+		if err != nil {
+			return err
+		}
+
+		c.recorder.Event(user, v2.EventTypeNormal, SuccessCreated, "Kubeconfig secret created successfully")
+		status.KubeconfigSecretName = desiredSecret.Name
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	desiredLabels, labelsChanged := mergeMetadata(secret.Labels, desiredSecret.Labels)
+	if labelsChanged || !bytes.Equal(secret.Data[KubeconfigSecretKey], desiredSecret.Data[KubeconfigSecretKey]) {
+		logger.Info("Kubeconfig secret is out of sync, resyncing", "secret", secret.Name)
+		secret = secret.DeepCopy()
+		secret.Labels = desiredLabels
+		secret.Data = desiredSecret.Data
+
+		if _, err = c.apiClient.Secrets(user.Namespace).Update(ctx, secret, v3.UpdateOptions{FieldManager: FieldManager}); err != nil {
+			return err
+		}
+	}
+
+	status.KubeconfigSecretName = desiredSecret.Name
+	return nil
+}
+
+func (c *Controller) KubeconfigSecretForUser(user *v1alpha2.User, token []byte, caData []byte, namespace string) (*v2.Secret, error) {
+	contextName := fmt.Sprintf("%v@%v", user.Name, c.options.ClusterName)
+
+	kubeconfig, err := clientcmd.Write(clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			c.options.ClusterName: {
+				Server:                   c.options.ClusterServer,
+				CertificateAuthorityData: caData,
+			},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			user.Name: {
+				Token: string(token),
+			},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			contextName: {
+				Cluster:   c.options.ClusterName,
+				AuthInfo:  user.Name,
+				Namespace: namespace,
+			},
+		},
+		CurrentContext: contextName,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &v2.Secret{
+		Type: v2.SecretTypeOpaque,
+		ObjectMeta: v3.ObjectMeta{
+			Name:      fmt.Sprintf("%v-kubeconfig", user.Name),
+			Namespace: user.Namespace,
+			Labels:    userLabels(user),
+			OwnerReferences: []v3.OwnerReference{
+				*v3.NewControllerRef(user, v1alpha2.SchemeGroupVersion.WithKind("User")),
+			},
+		},
+		Data: map[string][]byte{
+			KubeconfigSecretKey: kubeconfig,
+		},
+	}, nil
+}
+
+// defaultNamespace returns the namespace the kubeconfig of a user points to, which is the first namespace
+// that one of its groups grants access to
+func defaultNamespace(status *v1alpha2.UserStatus) string {
+	if len(status.BoundNamespaces) > 0 {
+		return status.BoundNamespaces[0]
+	}
+
+	return "default"
+}
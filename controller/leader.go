@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+// LeaderElectionOptions configures the Lease that is used to elect the replica that runs the workers
+type LeaderElectionOptions struct {
+	// Enabled makes the controller only run its workers while it holds the Lease
+	Enabled bool
+	// LeaseName and LeaseNamespace identify the Lease object
+	LeaseName      string
+	LeaseNamespace string
+	// Identity is the holder identity of this replica, it has to be unique across all replicas
+	Identity string
+	// LeaseDuration is how long other replicas wait before taking over a Lease that is no longer renewed
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader keeps retrying to renew the Lease before it gives up leadership
+	RenewDeadline time.Duration
+	// RetryPeriod is the interval between attempts to acquire or renew the Lease
+	RetryPeriod time.Duration
+}
+
+// runWithLeaderElection blocks until this replica holds the Lease and runs the workers until the context is cancelled
+// or the Lease is lost. On cancellation the workers are stopped first and the Lease is released afterwards, so that
+// another replica can take over immediately instead of waiting for the Lease to expire.
+func (c *Controller) runWithLeaderElection(ctx context.Context, workers int) error {
+	options := c.options.LeaderElection
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "lease", options.LeaseNamespace+"/"+options.LeaseName, "identity", options.Identity)
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: v3.ObjectMeta{
+			Name:      options.LeaseName,
+			Namespace: options.LeaseNamespace,
+		},
+		Client: c.kubeclientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity:      options.Identity,
+			EventRecorder: c.recorder,
+		},
+	}
+
+	// the elector releases the Lease as soon as its context is cancelled, so it gets a context that is only
+	// cancelled once the workers have finished their current items
+	leaseCtx, releaseLease := context.WithCancel(context.WithoutCancel(ctx))
+	defer releaseLease()
+
+	var leading atomic.Bool
+	workersStopped := make(chan struct{})
+
+	go func() {
+		<-ctx.Done()
+		if leading.Load() {
+			<-workersStopped
+		}
+		releaseLease()
+	}()
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		Name:            options.LeaseName,
+		LeaseDuration:   options.LeaseDuration,
+		RenewDeadline:   options.RenewDeadline,
+		RetryPeriod:     options.RetryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				leading.Store(true)
+				defer close(workersStopped)
+				logger.Info("Acquired leadership, starting workers")
+
+				workerCtx, cancel := context.WithCancel(leaderCtx)
+				defer cancel()
+				stop := context.AfterFunc(ctx, cancel)
+				defer stop()
+
+				c.runWorkers(workerCtx, workers)
+			},
+			OnStoppedLeading: func() {
+				logger.Info("Stopped leading")
+			},
+			OnNewLeader: func(identity string) {
+				if identity != options.Identity {
+					logger.Info("Another replica is leading, waiting for the Lease", "leader", identity)
+				}
+			},
+		},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Waiting to acquire leadership")
+	elector.Run(leaseCtx)
+
+	if ctx.Err() == nil {
+		return fmt.Errorf("lost leadership of lease %v/%v", options.LeaseNamespace, options.LeaseName)
+	}
+
+	return nil
+}
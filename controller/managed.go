@@ -0,0 +1,236 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+
+	v2 "k8s.io/api/core/v1"
+	v1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// userLabels are put on every object that is owned by a User
+func userLabels(user *v1alpha2.User) map[string]string {
+	return map[string]string{
+		LabelUser:      user.Name,
+		LabelNamespace: user.Namespace,
+	}
+}
+
+// groupLabels are put on every object that is owned by a Group
+func groupLabels(group *v1alpha2.Group) map[string]string {
+	return map[string]string{
+		LabelGroup:     group.Name,
+		LabelNamespace: group.Namespace,
+	}
+}
+
+// MembershipBindingName is the name of the RoleBindings and ClusterRoleBindings that grant a group to a user
+func MembershipBindingName(user string, group string) string {
+	return fmt.Sprintf("%v-membership-%v", user, group)
+}
+
+// membershipLabels are put on every binding that grants a group to a user
+func membershipLabels(user *v1alpha2.User, group *v1alpha2.Group) map[string]string {
+	return map[string]string{
+		LabelUser:      user.Name,
+		LabelNamespace: user.Namespace,
+		LabelGroup:     group.Name,
+	}
+}
+
+// mergeMetadata returns current with all entries of desired applied on top of it, and whether anything had to be changed.
+// Entries that are not managed by the controller are left untouched.
+func mergeMetadata(current map[string]string, desired map[string]string) (map[string]string, bool) {
+	changed := false
+	merged := maps.Clone(current)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+
+	for key, value := range desired {
+		if existing, ok := merged[key]; !ok || existing != value {
+			merged[key] = value
+			changed = true
+		}
+	}
+
+	return merged, changed
+}
+
+// enqueueOwnerOfManagedObject maps a changed or deleted managed object back to the User or Group it belongs to
+func (c *Controller) enqueueOwnerOfManagedObject(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	object, ok := obj.(v3.Object)
+	if !ok {
+		return
+	}
+
+	objectLabels := object.GetLabels()
+	namespace, ok := objectLabels[LabelNamespace]
+	if !ok {
+		return
+	}
+
+	if userName, ok := objectLabels[LabelUser]; ok {
+		c.userWorkqueue.Add(cache.ObjectName{Namespace: namespace, Name: userName})
+		return
+	}
+
+	if groupName, ok := objectLabels[LabelGroup]; ok {
+		c.groupWorkqueue.Add(cache.ObjectName{Namespace: namespace, Name: groupName})
+	}
+}
+
+// managedObjectEventHandler requeues the owner of a managed object whenever it is modified or deleted by someone else
+func (c *Controller) managedObjectEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			oldObj, oldOk := old.(v3.Object)
+			newObj, newOk := new.(v3.Object)
+			if oldOk && newOk && oldObj.GetResourceVersion() == newObj.GetResourceVersion() {
+				return
+			}
+			c.enqueueOwnerOfManagedObject(new)
+		},
+		DeleteFunc: c.enqueueOwnerOfManagedObject,
+	}
+}
+
+// ensureClusterRoleBinding creates the desired ClusterRoleBinding or restores it if it drifted.
+// As the RoleRef of a binding is immutable, a binding that references a different role is recreated.
+func (c *Controller) ensureClusterRoleBinding(ctx context.Context, owner runtime.Object, desired *v1.ClusterRoleBinding) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "clusterRoleBinding", desired.Name)
+	client := c.kubeclientset.RbacV1().ClusterRoleBindings()
+
+	current, err := client.Get(ctx, desired.Name, v3.GetOptions{})
+	if errors.IsNotFound(err) {
+		logger.Info("ClusterRoleBinding does not exist yet, creating")
+		_, err = client.Create(ctx, desired, v3.CreateOptions{FieldManager: FieldManager})
+		if err == nil {
+			c.recorder.Event(owner, v2.EventTypeNormal, SuccessCreated, "Created ClusterRoleBinding "+desired.Name)
+		}
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(current.RoleRef, desired.RoleRef) {
+		logger.Info("ClusterRoleBinding references a different role, recreating")
+		if err = client.Delete(ctx, current.Name, v3.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = client.Create(ctx, desired, v3.CreateOptions{FieldManager: FieldManager})
+		return err
+	}
+
+	updated := current.DeepCopy()
+	labelsChanged, annotationsChanged := false, false
+	updated.Labels, labelsChanged = mergeMetadata(current.Labels, desired.Labels)
+	updated.Annotations, annotationsChanged = mergeMetadata(current.Annotations, desired.Annotations)
+
+	if !labelsChanged && !annotationsChanged && reflect.DeepEqual(current.Subjects, desired.Subjects) {
+		return nil
+	}
+
+	logger.Info("ClusterRoleBinding is out of sync, resyncing")
+	updated.Subjects = desired.Subjects
+	_, err = client.Update(ctx, updated, v3.UpdateOptions{FieldManager: FieldManager})
+	return err
+}
+
+// ensureRoleBinding creates the desired RoleBinding or restores it if it drifted.
+// As the RoleRef of a binding is immutable, a binding that references a different role is recreated.
+func (c *Controller) ensureRoleBinding(ctx context.Context, owner runtime.Object, desired *v1.RoleBinding) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "roleBinding", desired.Name, "namespace", desired.Namespace)
+	client := c.kubeclientset.RbacV1().RoleBindings(desired.Namespace)
+
+	current, err := client.Get(ctx, desired.Name, v3.GetOptions{})
+	if errors.IsNotFound(err) {
+		logger.Info("RoleBinding does not exist yet, creating")
+		_, err = client.Create(ctx, desired, v3.CreateOptions{FieldManager: FieldManager})
+		if err == nil {
+			c.recorder.Event(owner, v2.EventTypeNormal, SuccessSynced, "Created RoleBinding in namespace "+desired.Namespace)
+		}
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(current.RoleRef, desired.RoleRef) {
+		logger.Info("RoleBinding references a different role, recreating")
+		if err = client.Delete(ctx, current.Name, v3.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = client.Create(ctx, desired, v3.CreateOptions{FieldManager: FieldManager})
+		return err
+	}
+
+	updated := current.DeepCopy()
+	labelsChanged, annotationsChanged := false, false
+	updated.Labels, labelsChanged = mergeMetadata(current.Labels, desired.Labels)
+	updated.Annotations, annotationsChanged = mergeMetadata(current.Annotations, desired.Annotations)
+
+	if !labelsChanged && !annotationsChanged && reflect.DeepEqual(current.Subjects, desired.Subjects) {
+		return nil
+	}
+
+	logger.Info("RoleBinding is out of sync, resyncing")
+	updated.Subjects = desired.Subjects
+	_, err = client.Update(ctx, updated, v3.UpdateOptions{FieldManager: FieldManager})
+	return err
+}
+
+// ensureRole creates the desired Role or restores it if it drifted
+func (c *Controller) ensureRole(ctx context.Context, owner runtime.Object, desired *v1.Role) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "role", desired.Name, "namespace", desired.Namespace)
+	client := c.kubeclientset.RbacV1().Roles(desired.Namespace)
+
+	current, err := client.Get(ctx, desired.Name, v3.GetOptions{})
+	if errors.IsNotFound(err) {
+		logger.Info("Role does not exist yet, creating")
+		_, err = client.Create(ctx, desired, v3.CreateOptions{FieldManager: FieldManager})
+		if err == nil {
+			c.recorder.Event(owner, v2.EventTypeNormal, SuccessCreated, "Created Role "+desired.Name+" in namespace "+desired.Namespace)
+		}
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Roles live in namespaces the group does not own, so one of the same name may have been created by someone else
+	if current.Labels[LabelGroup] != desired.Labels[LabelGroup] || current.Labels[LabelNamespace] != desired.Labels[LabelNamespace] {
+		return fmt.Errorf("%w: %v/%v", errRoleNotOwned, desired.Namespace, desired.Name)
+	}
+
+	updated := current.DeepCopy()
+	labelsChanged, annotationsChanged := false, false
+	updated.Labels, labelsChanged = mergeMetadata(current.Labels, desired.Labels)
+	updated.Annotations, annotationsChanged = mergeMetadata(current.Annotations, desired.Annotations)
+
+	if !labelsChanged && !annotationsChanged && equality.Semantic.DeepEqual(current.Rules, desired.Rules) {
+		return nil
+	}
+
+	logger.Info("Role is out of sync, resyncing")
+	updated.Rules = desired.Rules
+	_, err = client.Update(ctx, updated, v3.UpdateOptions{FieldManager: FieldManager})
+	return err
+}
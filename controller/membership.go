@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// activeGroupMemberships evaluates the memberships of the user at the given time. It returns the groups the user is
+// currently a member of, the state of every time-bound membership, and the next time at which one of them starts
+// or expires. The latter is zero if no membership changes in the future.
+func activeGroupMemberships(user *v1alpha2.User, now time.Time) ([]string, []v1alpha2.GroupMembershipStatus, time.Time) {
+	var active []string
+	var states []v1alpha2.GroupMembershipStatus
+	var nextChange time.Time
+
+	addActive := func(groupName string) {
+		if !slices.Contains(active, groupName) {
+			active = append(active, groupName)
+		}
+	}
+
+	scheduleChange := func(at time.Time) {
+		if nextChange.IsZero() || at.Before(nextChange) {
+			nextChange = at
+		}
+	}
+
+	for _, groupName := range user.Spec.GroupMemberships {
+		addActive(groupName)
+	}
+
+	for _, membership := range user.Spec.Memberships {
+		state := v1alpha2.GroupMembershipStatus{
+			Group:     membership.Group,
+			State:     v1alpha2.MembershipActive,
+			NotBefore: membership.NotBefore,
+			ExpiresAt: membership.ExpiresAt,
+		}
+
+		switch {
+		case membership.NotBefore != nil && now.Before(membership.NotBefore.Time):
+			state.State = v1alpha2.MembershipPending
+			scheduleChange(membership.NotBefore.Time)
+		case membership.ExpiresAt != nil && !now.Before(membership.ExpiresAt.Time):
+			state.State = v1alpha2.MembershipExpired
+		default:
+			addActive(membership.Group)
+			if membership.ExpiresAt != nil {
+				scheduleChange(membership.ExpiresAt.Time)
+			}
+		}
+
+		states = append(states, state)
+	}
+
+	return active, states, nextChange
+}
+
+// referencedGroups returns every group the user references, regardless of whether the membership is currently active
+func referencedGroups(user *v1alpha2.User) []string {
+	groups := slices.Clone(user.Spec.GroupMemberships)
+	for _, membership := range user.Spec.Memberships {
+		if !slices.Contains(groups, membership.Group) {
+			groups = append(groups, membership.Group)
+		}
+	}
+
+	return groups
+}
+
+// expandGroups resolves the includes of the given groups in the namespace. The result starts with the given groups,
+// followed by every group they include, transitively. Groups that do not exist or are being deleted are returned,
+// but their includes are not followed. It also returns every inclusion cycle that was found, which is otherwise ignored.
+func (c *Controller) expandGroups(namespace string, groups []string) ([]string, []string) {
+	expanded := slices.Clone(groups)
+	visited := map[string]bool{}
+	var cycles []string
+	var path []string
+
+	var visit func(groupName string)
+	visit = func(groupName string) {
+		if i := slices.Index(path, groupName); i >= 0 {
+			cycles = append(cycles, strings.Join(append(slices.Clone(path[i:]), groupName), " -> "))
+			return
+		}
+
+		if visited[groupName] {
+			return
+		}
+		visited[groupName] = true
+
+		group, err := c.groupLister.Groups(namespace).Get(groupName)
+		if err != nil || group.DeletionTimestamp != nil {
+			return
+		}
+
+		path = append(path, groupName)
+		for _, included := range group.Spec.Includes {
+			if !slices.Contains(expanded, included) {
+				expanded = append(expanded, included)
+			}
+			visit(included)
+		}
+		path = path[:len(path)-1]
+	}
+
+	for _, groupName := range groups {
+		visit(groupName)
+	}
+
+	return expanded, cycles
+}
+
+// includeProblems returns why includes of the group cannot be resolved, which are missing groups and inclusion cycles
+func (c *Controller) includeProblems(group *v1alpha2.Group) []string {
+	var problems []string
+
+	included, cycles := c.expandGroups(group.Namespace, []string{group.Name})
+	for _, groupName := range included[1:] {
+		if _, err := c.groupLister.Groups(group.Namespace).Get(groupName); err != nil {
+			problems = append(problems, fmt.Sprintf("included group %v does not exist", groupName))
+		}
+	}
+
+	for _, cycle := range cycles {
+		problems = append(problems, fmt.Sprintf("inclusion cycle %v is ignored", cycle))
+	}
+
+	return problems
+}
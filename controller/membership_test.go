@@ -0,0 +1,256 @@
+package controller
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	v2 "k8s.io/api/core/v1"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+	listers "perm8s/pkg/generated/listers/perm8s/v1alpha1"
+)
+
+// newGroupController returns a Controller whose group lister serves the given groups from the namespace team
+func newGroupController(t *testing.T, groups ...*v1alpha2.Group) *Controller {
+	t.Helper()
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, group := range groups {
+		group.Namespace = "team"
+		if err := indexer.Add(group); err != nil {
+			t.Fatalf("indexer.Add() error = %v", err)
+		}
+	}
+
+	return &Controller{groupLister: listers.NewGroupLister(indexer)}
+}
+
+func includingGroup(name string, includes ...string) *v1alpha2.Group {
+	return &v1alpha2.Group{
+		ObjectMeta: v3.ObjectMeta{Name: name},
+		Spec:       v1alpha2.GroupSpec{Includes: includes},
+	}
+}
+
+func TestExpandGroups(t *testing.T) {
+	deleting := includingGroup("deleting", "hidden")
+	deleting.DeletionTimestamp = &v3.Time{}
+
+	tests := []struct {
+		name         string
+		groups       []*v1alpha2.Group
+		roots        []string
+		wantExpanded []string
+		wantCycles   []string
+	}{
+		{
+			name:         "no includes",
+			groups:       []*v1alpha2.Group{includingGroup("a")},
+			roots:        []string{"a"},
+			wantExpanded: []string{"a"},
+		},
+		{
+			name:         "transitive includes",
+			groups:       []*v1alpha2.Group{includingGroup("a", "b"), includingGroup("b", "c"), includingGroup("c")},
+			roots:        []string{"a"},
+			wantExpanded: []string{"a", "b", "c"},
+		},
+		{
+			name:         "diamond",
+			groups:       []*v1alpha2.Group{includingGroup("a", "b", "c"), includingGroup("b", "d"), includingGroup("c", "d"), includingGroup("d")},
+			roots:        []string{"a"},
+			wantExpanded: []string{"a", "b", "d", "c"},
+		},
+		{
+			name:         "several roots sharing includes",
+			groups:       []*v1alpha2.Group{includingGroup("a", "c"), includingGroup("b", "c"), includingGroup("c")},
+			roots:        []string{"a", "b"},
+			wantExpanded: []string{"a", "b", "c"},
+		},
+		{
+			name:         "self cycle",
+			groups:       []*v1alpha2.Group{includingGroup("a", "a")},
+			roots:        []string{"a"},
+			wantExpanded: []string{"a"},
+			wantCycles:   []string{"a -> a"},
+		},
+		{
+			name:         "3-cycle",
+			groups:       []*v1alpha2.Group{includingGroup("a", "b"), includingGroup("b", "c"), includingGroup("c", "a")},
+			roots:        []string{"a"},
+			wantExpanded: []string{"a", "b", "c"},
+			wantCycles:   []string{"a -> b -> c -> a"},
+		},
+		{
+			name:         "cycle below the root",
+			groups:       []*v1alpha2.Group{includingGroup("a", "b"), includingGroup("b", "c"), includingGroup("c", "b")},
+			roots:        []string{"a"},
+			wantExpanded: []string{"a", "b", "c"},
+			wantCycles:   []string{"b -> c -> b"},
+		},
+		{
+			name:         "missing group",
+			groups:       []*v1alpha2.Group{includingGroup("a", "missing", "b"), includingGroup("b")},
+			roots:        []string{"a"},
+			wantExpanded: []string{"a", "missing", "b"},
+		},
+		{
+			name:         "missing root",
+			roots:        []string{"missing"},
+			wantExpanded: []string{"missing"},
+		},
+		{
+			name:         "deleting group",
+			groups:       []*v1alpha2.Group{includingGroup("a", "deleting"), deleting, includingGroup("hidden")},
+			roots:        []string{"a"},
+			wantExpanded: []string{"a", "deleting"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newGroupController(t, test.groups...)
+
+			expanded, cycles := c.expandGroups("team", test.roots)
+			if !reflect.DeepEqual(expanded, test.wantExpanded) {
+				t.Errorf("expandGroups() expanded = %v, want %v", expanded, test.wantExpanded)
+			}
+			if !reflect.DeepEqual(cycles, test.wantCycles) {
+				t.Errorf("expandGroups() cycles = %v, want %v", cycles, test.wantCycles)
+			}
+		})
+	}
+}
+
+func TestActiveGroupMemberships(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) *v3.Time {
+		timestamp := v3.NewTime(now.Add(offset))
+		return &timestamp
+	}
+
+	tests := []struct {
+		name           string
+		spec           v1alpha2.UserSpec
+		wantActive     []string
+		wantStates     []string
+		wantNextChange time.Time
+	}{
+		{
+			name:       "permanent memberships",
+			spec:       v1alpha2.UserSpec{GroupMemberships: []string{"developers"}, Memberships: []v1alpha2.GroupMembership{{Group: "operators"}}},
+			wantActive: []string{"developers", "operators"},
+			wantStates: []string{v1alpha2.MembershipActive},
+		},
+		{
+			name:           "not started yet",
+			spec:           v1alpha2.UserSpec{Memberships: []v1alpha2.GroupMembership{{Group: "operators", NotBefore: at(time.Hour), ExpiresAt: at(2 * time.Hour)}}},
+			wantStates:     []string{v1alpha2.MembershipPending},
+			wantNextChange: now.Add(time.Hour),
+		},
+		{
+			name:       "expired",
+			spec:       v1alpha2.UserSpec{Memberships: []v1alpha2.GroupMembership{{Group: "operators", NotBefore: at(-2 * time.Hour), ExpiresAt: at(-time.Hour)}}},
+			wantStates: []string{v1alpha2.MembershipExpired},
+		},
+		{
+			name:           "active until it expires",
+			spec:           v1alpha2.UserSpec{Memberships: []v1alpha2.GroupMembership{{Group: "operators", NotBefore: at(-time.Hour), ExpiresAt: at(time.Hour)}}},
+			wantActive:     []string{"operators"},
+			wantStates:     []string{v1alpha2.MembershipActive},
+			wantNextChange: now.Add(time.Hour),
+		},
+		{
+			name:           "starting at this instant",
+			spec:           v1alpha2.UserSpec{Memberships: []v1alpha2.GroupMembership{{Group: "operators", NotBefore: at(0), ExpiresAt: at(time.Hour)}}},
+			wantActive:     []string{"operators"},
+			wantStates:     []string{v1alpha2.MembershipActive},
+			wantNextChange: now.Add(time.Hour),
+		},
+		{
+			name:       "expiring at this instant",
+			spec:       v1alpha2.UserSpec{Memberships: []v1alpha2.GroupMembership{{Group: "operators", NotBefore: at(-time.Hour), ExpiresAt: at(0)}}},
+			wantStates: []string{v1alpha2.MembershipExpired},
+		},
+		{
+			name:           "starting one nanosecond later",
+			spec:           v1alpha2.UserSpec{Memberships: []v1alpha2.GroupMembership{{Group: "operators", NotBefore: at(time.Nanosecond)}}},
+			wantStates:     []string{v1alpha2.MembershipPending},
+			wantNextChange: now.Add(time.Nanosecond),
+		},
+		{
+			name: "earliest next change",
+			spec: v1alpha2.UserSpec{Memberships: []v1alpha2.GroupMembership{
+				{Group: "operators", ExpiresAt: at(3 * time.Hour)},
+				{Group: "admins", NotBefore: at(2 * time.Hour)},
+				{Group: "auditors", NotBefore: at(-time.Hour), ExpiresAt: at(time.Hour)},
+				{Group: "developers", ExpiresAt: at(-time.Hour)},
+			}},
+			wantActive:     []string{"operators", "auditors"},
+			wantStates:     []string{v1alpha2.MembershipActive, v1alpha2.MembershipPending, v1alpha2.MembershipActive, v1alpha2.MembershipExpired},
+			wantNextChange: now.Add(time.Hour),
+		},
+		{
+			name: "overlapping windows of the same group",
+			spec: v1alpha2.UserSpec{GroupMemberships: []string{"operators"}, Memberships: []v1alpha2.GroupMembership{
+				{Group: "operators", ExpiresAt: at(time.Hour)},
+				{Group: "operators", NotBefore: at(2 * time.Hour)},
+			}},
+			wantActive:     []string{"operators"},
+			wantStates:     []string{v1alpha2.MembershipActive, v1alpha2.MembershipPending},
+			wantNextChange: now.Add(time.Hour),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			active, states, nextChange := activeGroupMemberships(&v1alpha2.User{Spec: test.spec}, now)
+
+			if !reflect.DeepEqual(active, test.wantActive) {
+				t.Errorf("activeGroupMemberships() active = %v, want %v", active, test.wantActive)
+			}
+
+			var gotStates []string
+			for _, state := range states {
+				gotStates = append(gotStates, state.State)
+			}
+			if !reflect.DeepEqual(gotStates, test.wantStates) {
+				t.Errorf("activeGroupMemberships() states = %v, want %v", gotStates, test.wantStates)
+			}
+
+			if !nextChange.Equal(test.wantNextChange) {
+				t.Errorf("activeGroupMemberships() next change = %v, want %v", nextChange, test.wantNextChange)
+			}
+		})
+	}
+}
+
+func TestEnqueueGroupsOfNamespace(t *testing.T) {
+	selecting := includingGroup("selecting")
+	selecting.Spec.Namespaces = []string{"ci-*"}
+	unrelated := includingGroup("unrelated")
+	unrelated.Spec.Namespaces = []string{"prod-*"}
+
+	c := newGroupController(t, includingGroup("direct", "selecting"), includingGroup("transitive", "direct"), selecting, unrelated)
+	c.userLister = listers.NewUserLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}))
+	c.groupWorkqueue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer c.groupWorkqueue.ShutDown()
+
+	c.enqueueGroupsOfNamespace(&v2.Namespace{ObjectMeta: v3.ObjectMeta{Name: "ci-1234"}})
+
+	var enqueued []string
+	for c.groupWorkqueue.Len() > 0 {
+		item, _ := c.groupWorkqueue.Get()
+		enqueued = append(enqueued, item.(cache.ObjectName).Name)
+		c.groupWorkqueue.Done(item)
+	}
+	slices.Sort(enqueued)
+
+	if want := []string{"direct", "selecting", "transitive"}; !reflect.DeepEqual(enqueued, want) {
+		t.Errorf("enqueueGroupsOfNamespace() enqueued %v, want %v", enqueued, want)
+	}
+}
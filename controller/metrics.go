@@ -0,0 +1,233 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"k8s.io/apimachinery/pkg/labels"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const metricsNamespace = "perm8s"
+
+// Kinds used as label values of the reconcile metrics
+const (
+	kindUser          = "User"
+	kindGroup         = "Group"
+	kindSyncSource    = "SynchronisationSource"
+	kindAccessRequest = "AccessRequest"
+)
+
+// Operations used as label values of the user changes of a SynchronisationSource
+const (
+	operationCreated   = "created"
+	operationUpdated   = "updated"
+	operationSuspended = "suspended"
+	operationDeleted   = "deleted"
+)
+
+// Registry contains all metrics of the controller and is served on the metrics endpoint
+var Registry = prometheus.NewRegistry()
+
+var (
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Duration of a single reconciliation per kind.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"kind"})
+
+	reconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_errors_total",
+		Help:      "Number of reconciliations per kind that returned an error.",
+	}, []string{"kind"})
+
+	syncSourceUsers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "sync_source",
+		Name:      "users",
+		Help:      "Number of users returned by the upstream source during the last synchronisation.",
+	}, []string{"namespace", "source"})
+
+	syncSourceUserChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "sync_source",
+		Name:      "user_changes_total",
+		Help:      "Number of Users created, updated, suspended or deleted by a synchronisation source.",
+	}, []string{"namespace", "source", "operation"})
+
+	syncSourceLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "sync_source",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful synchronisation.",
+	}, []string{"namespace", "source"})
+
+	syncSourceFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "sync_source",
+		Name:      "fetch_duration_seconds",
+		Help:      "Latency of fetching the users from the upstream API of a synchronisation source.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"namespace", "source", "type"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		reconcileDuration,
+		reconcileErrors,
+		syncSourceUsers,
+		syncSourceUserChanges,
+		syncSourceLastSuccess,
+		syncSourceFetchDuration,
+		workqueueDepth,
+		workqueueAdds,
+		workqueueLatency,
+		workqueueWorkDuration,
+		workqueueUnfinishedWork,
+		workqueueLongestRunningProcessor,
+		workqueueRetries,
+	)
+
+	// the provider has to be set before any workqueue is created
+	workqueue.SetProvider(workqueueMetricsProvider{})
+}
+
+// observeReconcile records the duration and the outcome of a reconciliation of the given kind
+func observeReconcile(kind string, start time.Time, err error) {
+	reconcileDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+	if err != nil {
+		reconcileErrors.WithLabelValues(kind).Inc()
+	}
+}
+
+// forgetSyncSourceMetrics removes all series of a deleted synchronisation source
+func forgetSyncSourceMetrics(namespace string, name string) {
+	sourceLabels := prometheus.Labels{"namespace": namespace, "source": name}
+	syncSourceUsers.DeletePartialMatch(sourceLabels)
+	syncSourceUserChanges.DeletePartialMatch(sourceLabels)
+	syncSourceLastSuccess.DeletePartialMatch(sourceLabels)
+	syncSourceFetchDuration.DeletePartialMatch(sourceLabels)
+}
+
+// managedBindingsCollector counts the RoleBindings and ClusterRoleBindings managed by the controller from the informer caches
+type managedBindingsCollector struct {
+	roleBindingLister        rbaclisters.RoleBindingLister
+	clusterRoleBindingLister rbaclisters.ClusterRoleBindingLister
+	desc                     *prometheus.Desc
+}
+
+func newManagedBindingsCollector(roleBindingLister rbaclisters.RoleBindingLister, clusterRoleBindingLister rbaclisters.ClusterRoleBindingLister) *managedBindingsCollector {
+	return &managedBindingsCollector{
+		roleBindingLister:        roleBindingLister,
+		clusterRoleBindingLister: clusterRoleBindingLister,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "managed_bindings"),
+			"Number of RoleBindings and ClusterRoleBindings managed by the controller.",
+			[]string{"kind"}, nil,
+		),
+	}
+}
+
+func (m *managedBindingsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.desc
+}
+
+func (m *managedBindingsCollector) Collect(ch chan<- prometheus.Metric) {
+	if roleBindings, err := m.roleBindingLister.List(labels.Everything()); err == nil {
+		ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, float64(len(roleBindings)), "RoleBinding")
+	}
+
+	if clusterRoleBindings, err := m.clusterRoleBindingLister.List(labels.Everything()); err == nil {
+		ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, float64(len(clusterRoleBindings)), "ClusterRoleBinding")
+	}
+}
+
+var (
+	workqueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "depth",
+		Help:      "Current depth of the workqueue.",
+	}, []string{"name"})
+
+	workqueueAdds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "adds_total",
+		Help:      "Number of adds handled by the workqueue.",
+	}, []string{"name"})
+
+	workqueueLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "queue_duration_seconds",
+		Help:      "How long an item stays in the workqueue before it is processed.",
+		Buckets:   prometheus.ExponentialBuckets(10e-9, 10, 12),
+	}, []string{"name"})
+
+	workqueueWorkDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "work_duration_seconds",
+		Help:      "How long processing an item from the workqueue takes.",
+		Buckets:   prometheus.ExponentialBuckets(10e-9, 10, 12),
+	}, []string{"name"})
+
+	workqueueUnfinishedWork = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "unfinished_work_seconds",
+		Help:      "How long the items that are currently processed have been in progress.",
+	}, []string{"name"})
+
+	workqueueLongestRunningProcessor = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "longest_running_processor_seconds",
+		Help:      "How long the longest running item of the workqueue has been in progress.",
+	}, []string{"name"})
+
+	workqueueRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "retries_total",
+		Help:      "Number of retries handled by the workqueue.",
+	}, []string{"name"})
+)
+
+// workqueueMetricsProvider exposes the metrics of all named workqueues through the Registry
+type workqueueMetricsProvider struct{}
+
+func (workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return workqueueDepth.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return workqueueAdds.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return workqueueLatency.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return workqueueWorkDuration.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return workqueueUnfinishedWork.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return workqueueLongestRunningProcessor.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return workqueueRetries.WithLabelValues(name)
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	v2 "k8s.io/api/core/v1"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// isNamespacePattern reports whether an entry of GroupSpec.Namespaces is a glob pattern rather than a namespace name
+func isNamespacePattern(namespace string) bool {
+	return strings.ContainsAny(namespace, "*?[")
+}
+
+// namespaceSelection lists namespaces by name or glob pattern together with an optional label selector, as used by
+// groups and their namespace permissions
+type namespaceSelection struct {
+	namespaces []string
+	selector   *v3.LabelSelector
+}
+
+// namespaceSelections returns the namespaces of the group itself followed by those of every NamespacePermissions entry
+func namespaceSelections(group *v1alpha2.Group) []namespaceSelection {
+	selections := []namespaceSelection{{group.Spec.Namespaces, group.Spec.NamespaceSelector}}
+	for _, permissions := range group.Spec.NamespacePermissions {
+		selections = append(selections, namespaceSelection{permissions.Namespaces, permissions.NamespaceSelector})
+	}
+
+	return selections
+}
+
+// dynamic reports whether the selected namespaces depend on the namespaces that exist
+func (s namespaceSelection) dynamic() bool {
+	return s.selector != nil || slices.ContainsFunc(s.namespaces, isNamespacePattern)
+}
+
+// selectsNamespacesDynamically reports whether the namespaces of the group depend on the namespaces that exist
+func selectsNamespacesDynamically(group *v1alpha2.Group) bool {
+	return slices.ContainsFunc(namespaceSelections(group), namespaceSelection.dynamic)
+}
+
+// matches reports whether the namespace is selected by a glob pattern or the label selector
+func (s namespaceSelection) matches(namespace *v2.Namespace) (bool, error) {
+	for _, pattern := range s.namespaces {
+		if !isNamespacePattern(pattern) {
+			continue
+		}
+
+		if matched, err := path.Match(pattern, namespace.Name); err != nil {
+			return false, err
+		} else if matched {
+			return true, nil
+		}
+	}
+
+	if s.selector == nil {
+		return false, nil
+	}
+
+	selector, err := v3.LabelSelectorAsSelector(s.selector)
+	if err != nil {
+		return false, err
+	}
+
+	return selector.Matches(labels.Set(namespace.Labels)), nil
+}
+
+// matchesNamespace reports whether the namespace is selected by a glob pattern or a namespace selector of the group
+// or of one of its namespace permissions
+func matchesNamespace(group *v1alpha2.Group, namespace *v2.Namespace) (bool, error) {
+	for _, selection := range namespaceSelections(group) {
+		if matched, err := selection.matches(namespace); err != nil || matched {
+			return matched, err
+		}
+	}
+
+	return false, nil
+}
+
+// groupNamespaces resolves the namespaces a namespaced group grants its permissions in
+func (c *Controller) groupNamespaces(group *v1alpha2.Group) ([]string, error) {
+	return c.resolveNamespaces(namespaceSelection{group.Spec.Namespaces, group.Spec.NamespaceSelector})
+}
+
+// resolveNamespaces returns the namespaces of the selection. Namespaces that are listed by name are always returned,
+// namespaces matching a pattern or the selector only while they exist and are not terminating.
+func (c *Controller) resolveNamespaces(selection namespaceSelection) ([]string, error) {
+	var namespaces []string
+	for _, namespace := range selection.namespaces {
+		if !isNamespacePattern(namespace) && !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	if !selection.dynamic() {
+		return namespaces, nil
+	}
+
+	static := len(namespaces)
+	existing, err := c.namespaceLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, namespace := range existing {
+		if namespace.Status.Phase == v2.NamespaceTerminating || slices.Contains(namespaces, namespace.Name) {
+			continue
+		}
+
+		matched, err := selection.matches(namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		if matched {
+			namespaces = append(namespaces, namespace.Name)
+		}
+	}
+
+	// the lister returns the namespaces in no particular order, which would otherwise cause needless status updates
+	slices.Sort(namespaces[static:])
+	return namespaces, nil
+}
+
+// enqueueGroupsOfNamespace enqueues every group selecting the namespace through a pattern or a selector, together
+// with their members and the groups including them, so that their RoleBindings follow namespaces being created,
+// relabelled or deleted
+func (c *Controller) enqueueGroupsOfNamespace(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	namespace, ok := obj.(*v2.Namespace)
+	if !ok {
+		return
+	}
+
+	groups, err := c.groupLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	for _, group := range groups {
+		if !selectsNamespacesDynamically(group) {
+			continue
+		}
+
+		if matched, err := matchesNamespace(group, namespace); err != nil || !matched {
+			continue
+		}
+
+		c.groupWorkqueue.Add(cache.ObjectName{Namespace: group.Namespace, Name: group.Name})
+		c.enqueueIncludingGroups(group)
+		c.enqueueUsersOfGroup(group)
+	}
+}
+
+// namespaceLabelsChanged reports whether an update of a namespace could change which groups select it
+func namespaceLabelsChanged(old, new interface{}) bool {
+	oldNamespace, ok := old.(*v2.Namespace)
+	if !ok {
+		return true
+	}
+
+	newNamespace, ok := new.(*v2.Namespace)
+	if !ok {
+		return true
+	}
+
+	return !labels.Equals(oldNamespace.Labels, newNamespace.Labels) || oldNamespace.Status.Phase != newNamespace.Status.Phase
+}
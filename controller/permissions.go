@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	v4 "k8s.io/api/rbac/v1"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// reconcileClusterPermissions renders the cluster permissions of the group into their ClusterRole, or removes it once
+// the group no longer has any
+func (c *Controller) reconcileClusterPermissions(ctx context.Context, group *v1alpha2.Group, status *v1alpha2.GroupStatus) error {
+	if len(group.Spec.ClusterPermissions) == 0 {
+		return c.removeGroupClusterRole(ctx, group, ClusterPermissionsClusterRoleName(group))
+	}
+
+	clusterRole, err := c.ensureGroupClusterRole(ctx, group, c.ClusterPermissionsClusterRoleFromGroup(group))
+	if c.clusterRoleConflict(group, status, ClusterPermissionsClusterRoleName(group), err) {
+		return nil
+	}
+	if err != nil {
+		klog.FromContext(ctx).Error(err, "Error while syncing ClusterRole of cluster permissions", "group", group.Name, "clusterRoleName", ClusterPermissionsClusterRoleName(group))
+		return err
+	}
+
+	status.ClusterPermissionsRoleName = clusterRole.Name
+	return nil
+}
+
+// ClusterPermissionsClusterRoleFromGroup holds the cluster permissions of the group, which are bound cluster wide
+func (c *Controller) ClusterPermissionsClusterRoleFromGroup(group *v1alpha2.Group) *v4.ClusterRole {
+	return &v4.ClusterRole{
+		ObjectMeta: v3.ObjectMeta{
+			Name:   ClusterPermissionsClusterRoleName(group),
+			Labels: groupLabels(group),
+			OwnerReferences: []v3.OwnerReference{
+				*v3.NewControllerRef(group, v1alpha2.SchemeGroupVersion.WithKind("Group")),
+			},
+		},
+
+		Rules: group.Spec.ClusterPermissions,
+	}
+}
+
+// ClusterPermissionsClusterRoleName is separated from the name of the group by a colon, which cannot be part of the name
+// of a group, so it never collides with the ClusterRole of another group
+func ClusterPermissionsClusterRoleName(group *v1alpha2.Group) string {
+	return group.Name + ":cluster"
+}
+
+// reconcileNamespacePermissions renders a ClusterRole for every NamespacePermissions entry of the group that has
+// permissions and removes the ClusterRoles of entries that were removed, now reference an existing ClusterRole or
+// are rendered into namespaced Roles by reconcileGroupRoles
+func (c *Controller) reconcileNamespacePermissions(ctx context.Context, group *v1alpha2.Group, status *v1alpha2.GroupStatus) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "group", group.Name)
+
+	var desired []string
+	for _, permissions := range group.Spec.NamespacePermissions {
+		namespaces, err := c.resolveNamespaces(namespaceSelection{permissions.Namespaces, permissions.NamespaceSelector})
+		if err != nil {
+			return err
+		}
+
+		ref := namespacePermissionsRoleRef(group, permissions)
+		clusterRoleName := ""
+		if ref.Kind == "ClusterRole" {
+			clusterRoleName = ref.Name
+		}
+
+		if len(permissions.Permissions) > 0 && !group.Spec.NamespacedRoles {
+			_, err = c.ensureGroupClusterRole(ctx, group, c.NamespacePermissionsClusterRoleFromGroup(group, permissions))
+			if c.clusterRoleConflict(group, status, clusterRoleName, err) {
+				clusterRoleName = ""
+			} else if err != nil {
+				logger.Error(err, "Error while syncing ClusterRole of namespace permissions", "clusterRoleName", clusterRoleName)
+				return err
+			} else {
+				desired = append(desired, clusterRoleName)
+			}
+		}
+
+		status.NamespacePermissions = append(status.NamespacePermissions, v1alpha2.NamespacePermissionsStatus{
+			Name:            permissions.Name,
+			ClusterRoleName: clusterRoleName,
+			Namespaces:      namespaces,
+		})
+	}
+
+	clusterRoles, err := c.kubeclientset.RbacV1().ClusterRoles().List(ctx, v3.ListOptions{
+		LabelSelector: fmt.Sprintf("%v=%v,%v=%v,%v", LabelGroup, group.Name, LabelNamespace, group.Namespace, LabelNamespacePermissions),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, clusterRole := range clusterRoles.Items {
+		if !slices.Contains(desired, clusterRole.Name) {
+			if err = c.removeGroupClusterRole(ctx, group, clusterRole.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// NamespacePermissionsClusterRoleFromGroup holds the permissions of a NamespacePermissions entry of the group
+func (c *Controller) NamespacePermissionsClusterRoleFromGroup(group *v1alpha2.Group, permissions v1alpha2.NamespacePermissions) *v4.ClusterRole {
+	clusterRoleLabels := groupLabels(group)
+	clusterRoleLabels[LabelNamespacePermissions] = permissions.Name
+
+	return &v4.ClusterRole{
+		ObjectMeta: v3.ObjectMeta{
+			Name:   NamespacePermissionsClusterRoleName(group, permissions),
+			Labels: clusterRoleLabels,
+			OwnerReferences: []v3.OwnerReference{
+				*v3.NewControllerRef(group, v1alpha2.SchemeGroupVersion.WithKind("Group")),
+			},
+		},
+
+		Rules: permissions.Permissions,
+	}
+}
+
+func NamespacePermissionsClusterRoleName(group *v1alpha2.Group, permissions v1alpha2.NamespacePermissions) string {
+	return group.Name + ":ns:" + permissions.Name
+}
+
+// namespacePermissionsRoleRef returns the role that is bound for a NamespacePermissions entry
+func namespacePermissionsRoleRef(group *v1alpha2.Group, permissions v1alpha2.NamespacePermissions) v4.RoleRef {
+	if permissions.ClusterRoleRef != "" {
+		return clusterRoleRef(permissions.ClusterRoleRef)
+	}
+
+	if group.Spec.NamespacedRoles {
+		return roleRef(NamespacePermissionsClusterRoleName(group, permissions))
+	}
+
+	return clusterRoleRef(NamespacePermissionsClusterRoleName(group, permissions))
+}
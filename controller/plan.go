@@ -0,0 +1,369 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	v2 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+	"perm8s/sync"
+)
+
+const (
+	// planEventNameLimit is the number of user names listed per operation in the event of a plan
+	planEventNameLimit = 10
+	// AnnotationDeprovisionedAt records when a User was suspended because it is no longer returned by its SynchronisationSource
+	AnnotationDeprovisionedAt = "perm8s.tobiasgrether.com/deprovisioned-at"
+	// defaultDeprovisioningDeleteAfter is used by the suspendThenDelete policy when DeleteAfter is not set
+	defaultDeprovisioningDeleteAfter = 30 * 24 * time.Hour
+)
+
+// syncPlan holds the changes to the Users of a SynchronisationSource that are needed to match the upstream source
+type syncPlan struct {
+	create []*v1alpha2.User
+	// update and suspend contain copies of the current Users with the desired changes applied
+	update  []*v1alpha2.User
+	suspend []*v1alpha2.User
+	delete  []*v1alpha2.User
+	// owned is the number of Users of the source before the plan is applied
+	owned int
+	// invalid describes the users of the source that were skipped because no valid User can be created for them
+	invalid []string
+	// summary is the plan as published in the status of the source
+	summary v1alpha2.SynchronisationPlan
+}
+
+// planSyncSource compares the users returned by the source to the Users in the namespace of the source
+func (c *Controller) planSyncSource(ctx context.Context, source *v1alpha2.SynchronisationSource, users []sync.SyncUser) (*syncPlan, error) {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "source", source.Name)
+
+	existing, err := c.clientSet.Perm8sV1alpha1().Users(source.Namespace).List(ctx, v3.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	currentUsers := make(map[string]*v1alpha2.User, len(existing.Items))
+	for i := range existing.Items {
+		currentUsers[existing.Items[i].Name] = &existing.Items[i]
+	}
+
+	plan := &syncPlan{}
+	desiredNames := map[string]bool{}
+
+	for _, user := range users {
+		identifier := GetIdentifier(user.Name)
+
+		var groups []string
+
+		for _, g := range user.Groups {
+			if groupName, ok := source.Spec.GroupMappings[g]; ok {
+				groups = append(groups, groupName)
+			}
+		}
+
+		if source.Spec.DefaultGroups != nil {
+			groups = append(groups, *source.Spec.DefaultGroups...)
+		}
+
+		if problems := syncUserProblems(identifier, groups); len(problems) > 0 {
+			logger.Info("Source returned a user that cannot be turned into a valid User, skipping", "user", user.Name, "identifier", identifier, "problems", problems)
+			plan.invalid = append(plan.invalid, fmt.Sprintf("%q: %v", user.Name, strings.Join(problems, ", ")))
+			continue
+		}
+
+		if desiredNames[identifier] {
+			logger.Info("Source returned several users with the same identifier, ignoring duplicate", "user", user.Name, "identifier", identifier)
+			continue
+		}
+		desiredNames[identifier] = true
+
+		desiredUser := c.GetUserFromSyncUser(identifier, user.Name, source.Namespace, groups, source)
+
+		currentUser, ok := currentUsers[identifier]
+		if !ok {
+			plan.create = append(plan.create, desiredUser)
+			plan.summary.Create = append(plan.summary.Create, identifier)
+			continue
+		}
+
+		_, deprovisioned := currentUser.Annotations[AnnotationDeprovisionedAt]
+		updatedSpec := syncedUserSpec(currentUser.Spec, desiredUser.Spec, deprovisioned)
+
+		if deprovisioned || !reflect.DeepEqual(currentUser.Spec, updatedSpec) {
+			updatedUser := currentUser.DeepCopy()
+			updatedUser.OwnerReferences = desiredUser.OwnerReferences
+			updatedUser.Spec = updatedSpec
+			delete(updatedUser.Annotations, AnnotationDeprovisionedAt)
+
+			plan.update = append(plan.update, updatedUser)
+			plan.summary.Update = append(plan.summary.Update, v1alpha2.PlannedUserUpdate{
+				Name:          identifier,
+				AddedGroups:   missingFrom(desiredUser.Spec.GroupMemberships, currentUser.Spec.GroupMemberships),
+				RemovedGroups: missingFrom(currentUser.Spec.GroupMemberships, desiredUser.Spec.GroupMemberships),
+			})
+		}
+	}
+
+	// finally, we need to make sure no users exist that are not part of the target group anymore
+	for i := range existing.Items {
+		user := &existing.Items[i]
+		if user.Spec.AuthenticationSource != source.Name {
+			continue
+		}
+
+		plan.owned++
+		if desiredNames[user.Name] {
+			continue
+		}
+
+		policy, deleteAfter := deprovisioningPolicy(source)
+		if policy == v1alpha2.DeprovisioningDelete {
+			plan.delete = append(plan.delete, user)
+			plan.summary.Delete = append(plan.summary.Delete, user.Name)
+			continue
+		}
+
+		deprovisionedAt, err := time.Parse(time.RFC3339, user.Annotations[AnnotationDeprovisionedAt])
+		if err != nil {
+			suspendedUser := user.DeepCopy()
+			suspendedUser.Spec.Suspended = true
+			if suspendedUser.Annotations == nil {
+				suspendedUser.Annotations = map[string]string{}
+			}
+			suspendedUser.Annotations[AnnotationDeprovisionedAt] = time.Now().UTC().Format(time.RFC3339)
+
+			plan.suspend = append(plan.suspend, suspendedUser)
+			plan.summary.Suspend = append(plan.summary.Suspend, user.Name)
+			continue
+		}
+
+		if policy == v1alpha2.DeprovisioningSuspendThenDelete && time.Since(deprovisionedAt) >= deleteAfter {
+			plan.delete = append(plan.delete, user)
+			plan.summary.Delete = append(plan.summary.Delete, user.Name)
+		}
+	}
+
+	return plan, nil
+}
+
+// syncedUserSpec applies the fields a SynchronisationSource owns to the spec of an existing User. Everything else,
+// like time-bound memberships, the identity, the token or the ServiceAccount setting, is managed by admins and kept.
+// Suspensions by an admin are kept as well, while a User that was suspended by its deprovisioning returned to the source.
+func syncedUserSpec(current v1alpha2.UserSpec, desired v1alpha2.UserSpec, deprovisioned bool) v1alpha2.UserSpec {
+	spec := *current.DeepCopy()
+	spec.DisplayName = desired.DisplayName
+	spec.AuthenticationSource = desired.AuthenticationSource
+	spec.Suspended = current.Suspended && !deprovisioned
+
+	// an empty list and a missing one are the same to the API server, replacing one with the other would cause an update on every sync
+	if len(desired.GroupMemberships) > 0 || len(current.GroupMemberships) > 0 {
+		spec.GroupMemberships = desired.GroupMemberships
+	}
+
+	return spec
+}
+
+// applySyncPlan creates, updates, suspends and deletes the Users of the plan
+func (c *Controller) applySyncPlan(ctx context.Context, source *v1alpha2.SynchronisationSource, plan *syncPlan) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "source", source.Name)
+	client := c.clientSet.Perm8sV1alpha1().Users(source.Namespace)
+
+	for _, user := range plan.create {
+		logger.Info("User account does not exist for external identity user yet, creating new", "user", user.Name)
+		if _, err := client.Create(ctx, user, v3.CreateOptions{}); err != nil {
+			return err
+		}
+
+		syncSourceUserChanges.WithLabelValues(source.Namespace, source.Name, operationCreated).Inc()
+		c.recorder.Event(source, v2.EventTypeNormal, SuccessSynced, "User Account created for external users")
+	}
+
+	for _, user := range plan.update {
+		logger.Info("External User is out of sync, resynching", "user", user.Name)
+		if _, err := client.Update(ctx, user, v3.UpdateOptions{}); err != nil {
+			return err
+		}
+
+		syncSourceUserChanges.WithLabelValues(source.Namespace, source.Name, operationUpdated).Inc()
+	}
+
+	for _, user := range plan.suspend {
+		logger.Info("User is no longer returned by the source and will be suspended", "user", user.Name)
+		if _, err := client.Update(ctx, user, v3.UpdateOptions{}); err != nil {
+			return err
+		}
+
+		syncSourceUserChanges.WithLabelValues(source.Namespace, source.Name, operationSuspended).Inc()
+		c.recorder.Event(source, v2.EventTypeNormal, ReasonSuspended, "Suspended User "+user.Name+" which is no longer returned by the source")
+	}
+
+	for _, user := range plan.delete {
+		logger.Info("User is orphaned and will be deleted", "user", user.Name, "namespace", user.Namespace)
+
+		if err := client.Delete(ctx, user.Name, v3.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Error during deletion of orphaned User", "user", user.Name, "namespace", user.Namespace)
+			return err
+		}
+
+		syncSourceUserChanges.WithLabelValues(source.Namespace, source.Name, operationDeleted).Inc()
+		logger.Info("Orphaned user deleted successfully", "user", user.Name, "namespace", user.Namespace)
+	}
+
+	return nil
+}
+
+// isEmpty reports whether applying the plan would not change anything
+func (p *syncPlan) isEmpty() bool {
+	return len(p.create) == 0 && len(p.update) == 0 && len(p.suspend) == 0 && len(p.delete) == 0
+}
+
+// describe renders the plan into a single line for Events
+func (p *syncPlan) describe() string {
+	if p.isEmpty() {
+		return "Plan: all Users are in sync"
+	}
+
+	updates := make([]string, 0, len(p.summary.Update))
+	for _, update := range p.summary.Update {
+		var changes []string
+		for _, group := range update.AddedGroups {
+			changes = append(changes, "+"+group)
+		}
+		for _, group := range update.RemovedGroups {
+			changes = append(changes, "-"+group)
+		}
+		updates = append(updates, fmt.Sprintf("%v (%v)", update.Name, strings.Join(changes, " ")))
+	}
+
+	return fmt.Sprintf("Plan: create %v, update %v, suspend %v, delete %v",
+		limitNames(p.summary.Create), limitNames(updates), limitNames(p.summary.Suspend), limitNames(p.summary.Delete))
+}
+
+// limitNames lists at most planEventNameLimit names, so that events of large plans stay readable
+func limitNames(names []string) string {
+	if len(names) <= planEventNameLimit {
+		return fmt.Sprintf("%d [%v]", len(names), strings.Join(names, ", "))
+	}
+
+	return fmt.Sprintf("%d [%v, and %d more]", len(names), strings.Join(names[:planEventNameLimit], ", "), len(names)-planEventNameLimit)
+}
+
+// deprovisioningPolicy returns the deprovisioning policy of the source and, for suspendThenDelete, how long Users stay suspended
+func deprovisioningPolicy(source *v1alpha2.SynchronisationSource) (string, time.Duration) {
+	if source.Spec.Deprovisioning == nil || source.Spec.Deprovisioning.Policy == "" {
+		return v1alpha2.DeprovisioningDelete, 0
+	}
+
+	deleteAfter := defaultDeprovisioningDeleteAfter
+	if source.Spec.Deprovisioning.DeleteAfter != nil {
+		deleteAfter = source.Spec.Deprovisioning.DeleteAfter.Duration
+	}
+
+	return source.Spec.Deprovisioning.Policy, deleteAfter
+}
+
+// missingFrom returns all entries of values that are not contained in other
+func missingFrom(values []string, other []string) []string {
+	var missing []string
+	for _, value := range values {
+		if !slices.Contains(other, value) && !slices.Contains(missing, value) {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+// syncUserProblems checks the name a user of a source is stored under, as well as the names of the bindings of its
+// memberships. The name is also put into the labels of the objects of the user, so it has to be a valid label value.
+// The API server would reject a User or binding with an invalid name, which would fail the whole synchronisation
+func syncUserProblems(identifier string, groups []string) []string {
+	var problems []string
+
+	for _, msg := range validation.IsDNS1123Subdomain(identifier) {
+		problems = append(problems, fmt.Sprintf("identifier %q is invalid: %v", identifier, msg))
+	}
+
+	for _, msg := range validation.IsValidLabelValue(identifier) {
+		problems = append(problems, fmt.Sprintf("identifier %q cannot be used as a label value: %v", identifier, msg))
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+
+	for _, groupName := range groups {
+		for _, msg := range validation.IsDNS1123Subdomain(MembershipBindingName(identifier, groupName)) {
+			problems = append(problems, fmt.Sprintf("binding name for group %q is invalid: %v", groupName, msg))
+		}
+	}
+
+	return problems
+}
+
+// deletionOverrideRequested reports whether a new value of the allow-mass-deletion annotation was set since the last
+// synchronisation that made use of it
+func deletionOverrideRequested(source *v1alpha2.SynchronisationSource) bool {
+	value := source.Annotations[v1alpha2.AllowMassDeletionAnnotation]
+	return value != "" && value != source.Status.LastHandledDeletionOverride
+}
+
+// checkDeletionLimit records in the DeletionLimitExceeded condition whether the plan deletes more Users than the deletion
+// limit of the source allows, and returns an error if it does and the limit was not overridden through the annotation.
+// It reports whether the override is needed, which is only used up once the plan has been applied
+func (c *Controller) checkDeletionLimit(source *v1alpha2.SynchronisationSource, plan *syncPlan, status *v1alpha2.SynchronisationSourceStatus) (bool, error) {
+	condition := v3.Condition{
+		Type:               v1alpha2.ConditionDeletionLimitExceeded,
+		Status:             v3.ConditionFalse,
+		ObservedGeneration: source.Generation,
+		Reason:             ReasonWithinDeletionLimit,
+	}
+	defer func() {
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}()
+
+	// suspending a User removes its access just like deleting it, so both count towards the limit
+	limit := source.Spec.DeletionLimit
+	deletions := len(plan.delete) + len(plan.suspend)
+	if limit == nil || deletions == 0 {
+		return false, nil
+	}
+
+	var violations []string
+
+	if limit.MaxDeletions != nil && deletions > int(*limit.MaxDeletions) {
+		violations = append(violations, fmt.Sprintf("%d Users would be deleted or suspended, but at most %d are allowed", deletions, *limit.MaxDeletions))
+	}
+
+	// compared without dividing, so that f.e. 20.5% do not pass a limit of 20% by rounding down
+	if limit.MaxDeletionPercentage != nil && plan.owned > 0 && deletions*100 > int(*limit.MaxDeletionPercentage)*plan.owned {
+		percentage := float64(deletions) * 100 / float64(plan.owned)
+		violations = append(violations, fmt.Sprintf("%.1f%% of the Users would be deleted or suspended, but at most %d%% are allowed", percentage, *limit.MaxDeletionPercentage))
+	}
+
+	if len(violations) == 0 {
+		return false, nil
+	}
+
+	message := strings.Join(violations, "; ")
+
+	if deletionOverrideRequested(source) && source.Spec.Mode != v1alpha2.SyncModePlan {
+		condition.Reason = ReasonDeletionLimitOverridden
+		condition.Message = message + ", overridden through the " + v1alpha2.AllowMassDeletionAnnotation + " annotation"
+		c.recorder.Event(source, v2.EventTypeWarning, ReasonDeletionLimitOverridden, condition.Message)
+		return true, nil
+	}
+
+	condition.Status = v3.ConditionTrue
+	condition.Reason = ReasonDeletionLimitExceeded
+	condition.Message = message
+	return false, fmt.Errorf("%w: %v", errDeletionLimitExceeded, message)
+}
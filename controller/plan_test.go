@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+func TestSyncedUserSpec(t *testing.T) {
+	serviceAccount := false
+	expiresAt := v3.NewTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	adminManaged := v1alpha2.UserSpec{
+		DisplayName:          "Jane Doe",
+		AuthenticationSource: "acme",
+		GroupMemberships:     []string{"developers"},
+		Memberships:          []v1alpha2.GroupMembership{{Group: "on-call", ExpiresAt: &expiresAt}},
+		Token:                &v1alpha2.UserTokenSpec{Mode: "tokenRequest"},
+		Identity:             &v1alpha2.ExternalIdentity{Username: "jane@example.com"},
+		ServiceAccount:       &serviceAccount,
+	}
+
+	tests := []struct {
+		name          string
+		current       v1alpha2.UserSpec
+		desired       v1alpha2.UserSpec
+		deprovisioned bool
+		want          v1alpha2.UserSpec
+	}{
+		{
+			name:    "unchanged user keeps fields the source does not own",
+			current: adminManaged,
+			desired: v1alpha2.UserSpec{DisplayName: "Jane Doe", AuthenticationSource: "acme", GroupMemberships: []string{"developers"}},
+			want:    adminManaged,
+		},
+		{
+			name:    "group changes of the source are applied",
+			current: adminManaged,
+			desired: v1alpha2.UserSpec{DisplayName: "Jane Doe", AuthenticationSource: "acme", GroupMemberships: []string{"developers", "sre"}},
+			want: func() v1alpha2.UserSpec {
+				spec := *adminManaged.DeepCopy()
+				spec.GroupMemberships = []string{"developers", "sre"}
+				return spec
+			}(),
+		},
+		{
+			name:    "missing and empty groups are equal",
+			current: v1alpha2.UserSpec{DisplayName: "Jane Doe", AuthenticationSource: "acme", GroupMemberships: []string{}},
+			desired: v1alpha2.UserSpec{DisplayName: "Jane Doe", AuthenticationSource: "acme"},
+			want:    v1alpha2.UserSpec{DisplayName: "Jane Doe", AuthenticationSource: "acme", GroupMemberships: []string{}},
+		},
+		{
+			name:    "suspension by an admin is kept",
+			current: v1alpha2.UserSpec{DisplayName: "Jane Doe", AuthenticationSource: "acme", Suspended: true},
+			desired: v1alpha2.UserSpec{DisplayName: "Jane Doe", AuthenticationSource: "acme"},
+			want:    v1alpha2.UserSpec{DisplayName: "Jane Doe", AuthenticationSource: "acme", Suspended: true},
+		},
+		{
+			name:          "deprovisioned user is reactivated",
+			current:       v1alpha2.UserSpec{DisplayName: "Jane Doe", AuthenticationSource: "acme", Suspended: true},
+			desired:       v1alpha2.UserSpec{DisplayName: "Jane Doe", AuthenticationSource: "acme"},
+			deprovisioned: true,
+			want:          v1alpha2.UserSpec{DisplayName: "Jane Doe", AuthenticationSource: "acme"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := syncedUserSpec(test.current, test.desired, test.deprovisioned); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("syncedUserSpec() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckDeletionLimit(t *testing.T) {
+	limit := func(maxDeletions *int32, maxPercentage *int32) *v1alpha2.SynchronisationDeletionLimit {
+		return &v1alpha2.SynchronisationDeletionLimit{MaxDeletions: maxDeletions, MaxDeletionPercentage: maxPercentage}
+	}
+	count := func(value int32) *int32 {
+		return &value
+	}
+
+	tests := []struct {
+		name        string
+		limit       *v1alpha2.SynchronisationDeletionLimit
+		mode        string
+		override    string
+		handled     string
+		deletions   int
+		suspensions int
+		owned       int
+		exceeded    bool
+		overridden  bool
+	}{
+		{name: "no limit", deletions: 200, owned: 200},
+		{name: "percentage at the limit", limit: limit(nil, count(20)), deletions: 40, owned: 200},
+		{name: "percentage rounding down to the limit", limit: limit(nil, count(20)), deletions: 41, owned: 200, exceeded: true},
+		{name: "suspensions count as deletions", limit: limit(nil, count(20)), deletions: 20, suspensions: 21, owned: 200, exceeded: true},
+		{name: "maximum number at the limit", limit: limit(count(5), nil), deletions: 5, owned: 200},
+		{name: "maximum number exceeded", limit: limit(count(5), nil), deletions: 6, owned: 200, exceeded: true},
+		{name: "no users owned yet", limit: limit(nil, count(20)), owned: 0},
+		{name: "override", limit: limit(count(5), nil), override: "1", deletions: 6, owned: 200, overridden: true},
+		{name: "override already used", limit: limit(count(5), nil), override: "1", handled: "1", deletions: 6, owned: 200, exceeded: true},
+		{name: "override ignored in plan mode", limit: limit(count(5), nil), mode: v1alpha2.SyncModePlan, override: "1", deletions: 6, owned: 200, exceeded: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := &v1alpha2.SynchronisationSource{
+				ObjectMeta: v3.ObjectMeta{Name: "acme", Namespace: "team", Generation: 1},
+				Spec:       v1alpha2.SynchronisationSourceSpec{Mode: test.mode, DeletionLimit: test.limit},
+				Status:     v1alpha2.SynchronisationSourceStatus{LastHandledDeletionOverride: test.handled},
+			}
+			if test.override != "" {
+				source.Annotations = map[string]string{v1alpha2.AllowMassDeletionAnnotation: test.override}
+			}
+
+			plan := &syncPlan{
+				delete:  make([]*v1alpha2.User, test.deletions),
+				suspend: make([]*v1alpha2.User, test.suspensions),
+				owned:   test.owned,
+			}
+
+			c := &Controller{recorder: record.NewFakeRecorder(10)}
+			status := &v1alpha2.SynchronisationSourceStatus{}
+
+			overridden, err := c.checkDeletionLimit(source, plan, status)
+			if exceeded := errors.Is(err, errDeletionLimitExceeded); exceeded != test.exceeded {
+				t.Errorf("checkDeletionLimit() error = %v, want exceeded %v", err, test.exceeded)
+			}
+			if overridden != test.overridden {
+				t.Errorf("checkDeletionLimit() overridden = %v, want %v", overridden, test.overridden)
+			}
+			if condition := meta.IsStatusConditionTrue(status.Conditions, v1alpha2.ConditionDeletionLimitExceeded); condition != test.exceeded {
+				t.Errorf("DeletionLimitExceeded condition = %v, want %v", condition, test.exceeded)
+			}
+		})
+	}
+}
+
+func TestSyncUserProblems(t *testing.T) {
+	tests := []struct {
+		name       string
+		identifier string
+		groups     []string
+		valid      bool
+	}{
+		{name: "regular user", identifier: GetIdentifier("Jane Doe"), groups: []string{"developers"}, valid: true},
+		{name: "name without letters or digits", identifier: GetIdentifier("Ñ¿?"), valid: false},
+		{name: "empty name", identifier: GetIdentifier("  "), valid: false},
+		{name: "name that is too long", identifier: GetIdentifier(strings.Repeat("a", 254)), valid: false},
+		{name: "name that is too long for a label", identifier: GetIdentifier(strings.Repeat("a", 64)), valid: false},
+		{name: "name of the maximum label length", identifier: GetIdentifier(strings.Repeat("a", 63)), groups: []string{"developers"}, valid: true},
+		{name: "binding name that is too long", identifier: GetIdentifier(strings.Repeat("a", 60)), groups: []string{strings.Repeat("b", 200)}, valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if problems := syncUserProblems(test.identifier, test.groups); (len(problems) == 0) != test.valid {
+				t.Errorf("syncUserProblems(%q) = %v, want valid %v", test.identifier, problems, test.valid)
+			}
+		})
+	}
+}
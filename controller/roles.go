@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+
+	v2 "k8s.io/api/core/v1"
+	v4 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// errRoleNotOwned is returned by ensureRole for a Role of the desired name that was not created for the group
+var errRoleNotOwned = stderrors.New("Role exists but does not belong to the group")
+
+// hasGeneratedRole reports whether the permissions of the group are rendered into a Role in each of its namespaces
+func hasGeneratedRole(group *v1alpha2.Group) bool {
+	return group.Spec.NamespacedRoles && !group.Spec.ClusterGroup && !isAggregated(group) && len(group.Spec.Permissions) > 0
+}
+
+// reconcileGroupRoles renders the Roles of a group that uses namespaced Roles into each of its namespaces and removes
+// the Roles of namespaces the group or one of its NamespacePermissions entries no longer applies to. Existing Roles of
+// the same name that do not carry the labels of the group are left alone and recorded as conflicts in the status
+func (c *Controller) reconcileGroupRoles(ctx context.Context, group *v1alpha2.Group, status *v1alpha2.GroupStatus) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "group", group.Name)
+
+	var desired []*v4.Role
+	if hasGeneratedRole(group) {
+		namespaces, err := c.groupNamespaces(group)
+		if err != nil {
+			return err
+		}
+
+		for _, namespace := range namespaces {
+			desired = append(desired, c.RoleFromGroup(group, namespace, group.Name, group.Spec.Permissions, groupLabels(group)))
+		}
+	}
+
+	if group.Spec.NamespacedRoles {
+		for _, permissions := range group.Spec.NamespacePermissions {
+			if len(permissions.Permissions) == 0 {
+				continue
+			}
+
+			namespaces, err := c.resolveNamespaces(namespaceSelection{permissions.Namespaces, permissions.NamespaceSelector})
+			if err != nil {
+				return err
+			}
+
+			roleLabels := groupLabels(group)
+			roleLabels[LabelNamespacePermissions] = permissions.Name
+			for _, namespace := range namespaces {
+				desired = append(desired, c.RoleFromGroup(group, namespace, NamespacePermissionsClusterRoleName(group, permissions), permissions.Permissions, roleLabels))
+			}
+		}
+	}
+
+	for _, role := range desired {
+		err := c.ensureRole(ctx, group, role)
+		if stderrors.Is(err, errRoleNotOwned) {
+			logger.Info("Role already exists without the labels of the group, leaving it untouched", "namespace", role.Namespace, "role", role.Name)
+			c.recorder.Event(group, v2.EventTypeWarning, ReasonRoleConflict, err.Error())
+			status.ConflictingRoles = append(status.ConflictingRoles, fmt.Sprintf("%v/%v", role.Namespace, role.Name))
+			continue
+		}
+		if err != nil {
+			logger.Error(err, "Error while syncing Role", "namespace", role.Namespace, "role", role.Name)
+			return err
+		}
+		status.Roles = append(status.Roles, fmt.Sprintf("%v/%v", role.Namespace, role.Name))
+	}
+
+	roles, err := c.kubeclientset.RbacV1().Roles("").List(ctx, v3.ListOptions{
+		LabelSelector: fmt.Sprintf("%v=%v,%v=%v", LabelGroup, group.Name, LabelNamespace, group.Namespace),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, role := range roles.Items {
+		if slices.Contains(status.Roles, fmt.Sprintf("%v/%v", role.Namespace, role.Name)) {
+			continue
+		}
+
+		logger.Info("Role is no longer needed by the group, deleting", "namespace", role.Namespace, "role", role.Name)
+		err = c.kubeclientset.RbacV1().Roles(role.Namespace).Delete(ctx, role.Name, v3.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// roleConflicts reports whether the Role of the given name in the namespace belongs to someone else than the group
+func roleConflicts(group *v1alpha2.Group, namespace string, name string) bool {
+	return slices.Contains(group.Status.ConflictingRoles, fmt.Sprintf("%v/%v", namespace, name))
+}
+
+// RoleFromGroup renders permissions of the group into a Role within the given namespace. Owner references cannot
+// point across namespaces, so the Role is mapped back to its group by its labels only
+func (c *Controller) RoleFromGroup(group *v1alpha2.Group, namespace string, name string, rules []v4.PolicyRule, roleLabels map[string]string) *v4.Role {
+	return &v4.Role{
+		ObjectMeta: v3.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    roleLabels,
+		},
+
+		Rules: rules,
+	}
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v4 "k8s.io/api/rbac/v1"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+func TestGroupRolesSkipUnownedRoles(t *testing.T) {
+	rules := []v4.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}}
+	foreignRules := []v4.PolicyRule{{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}}
+
+	group := &v1alpha2.Group{
+		ObjectMeta: v3.ObjectMeta{Name: "developers", Namespace: "team"},
+		Spec: v1alpha2.GroupSpec{
+			Namespaces:      []string{"frontend", "backend"},
+			NamespacedRoles: true,
+			Permissions:     rules,
+		},
+	}
+
+	// a Role of the same name someone else created in one of the namespaces of the group
+	foreign := &v4.Role{
+		ObjectMeta: v3.ObjectMeta{Name: "developers", Namespace: "backend"},
+		Rules:      foreignRules,
+	}
+
+	kubeClient := fake.NewSimpleClientset(foreign)
+	c := &Controller{kubeclientset: kubeClient, recorder: record.NewFakeRecorder(10)}
+
+	status := v1alpha2.GroupStatus{}
+	if err := c.reconcileGroupRoles(context.Background(), group, &status); err != nil {
+		t.Fatalf("reconcileGroupRoles() error = %v", err)
+	}
+
+	if want := []string{"frontend/developers"}; !reflect.DeepEqual(status.Roles, want) {
+		t.Errorf("Roles = %v, want %v", status.Roles, want)
+	}
+	if want := []string{"backend/developers"}; !reflect.DeepEqual(status.ConflictingRoles, want) {
+		t.Errorf("ConflictingRoles = %v, want %v", status.ConflictingRoles, want)
+	}
+
+	role, err := kubeClient.RbacV1().Roles("backend").Get(context.Background(), "developers", v3.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(role.Rules, foreignRules) || len(role.Labels) > 0 {
+		t.Errorf("Role that does not belong to the group was modified: %+v", role)
+	}
+
+	group.Status = status
+	_, roleBindings, err := c.groupBindings(group, bindingTemplate{name: "jane-membership-developers"})
+	if err != nil {
+		t.Fatalf("groupBindings() error = %v", err)
+	}
+
+	var namespaces []string
+	for _, roleBinding := range roleBindings {
+		namespaces = append(namespaces, roleBinding.Namespace)
+	}
+	if want := []string{"frontend"}; !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("RoleBindings created in %v, want %v", namespaces, want)
+	}
+}
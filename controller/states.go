@@ -11,4 +11,54 @@ const (
     MessageUserCreated = "User created successfully"
     MessageGroupSynced = "Group synced successfully"
     FieldManager = controllerAgentName
-)
\ No newline at end of file
+    // GroupFinalizer makes sure that the ClusterRole and all bindings of a Group are removed before the Group itself
+    GroupFinalizer = "perm8s.tobiasgrether.com/group-cleanup"
+)
+
+// Labels put on every object managed by the controller to map it back to its owning User or Group
+const (
+    LabelUser      = "perm8s.tobiasgrether.com/user"
+    LabelGroup     = "perm8s.tobiasgrether.com/group"
+    LabelNamespace = "perm8s.tobiasgrether.com/namespace"
+    // LabelAggregateToGroup selects the ClusterRole holding the permissions of an aggregated group
+    LabelAggregateToGroup = "perm8s.tobiasgrether.com/aggregate-to-group"
+    // LabelNamespacePermissions names the NamespacePermissions entry a ClusterRole of a group was rendered from
+    LabelNamespacePermissions = "perm8s.tobiasgrether.com/namespace-permissions"
+    // ManagedLabelSelector selects every object that is managed by this controller
+    ManagedLabelSelector = LabelNamespace
+)
+
+// aggregateToLabelPrefix is the prefix of the labels that aggregate a ClusterRole into the built-in user-facing roles
+const aggregateToLabelPrefix = "rbac.authorization.k8s.io/aggregate-to-"
+
+// aggregateToLabels are the labels a Group can put on its ClusterRole through AggregateTo
+var aggregateToLabels = []string{aggregateToLabelPrefix + "view", aggregateToLabelPrefix + "edit", aggregateToLabelPrefix + "admin"}
+
+// Reasons used for the status conditions of all resources
+const (
+    ReasonReconciled      = "Reconciled"
+    ReasonReconcileFailed = "ReconcileFailed"
+    ReasonGroupNotFound   = "GroupNotFound"
+    ReasonSourceError     = "SourceError"
+    ReasonSourceReachable = "SourceReachable"
+    // ReasonInvalidIdentifier is used when users of a SynchronisationSource cannot be stored under a valid name
+    ReasonInvalidIdentifier = "InvalidIdentifier"
+    // ReasonPlanned is used for the events of SynchronisationSources in plan mode
+    ReasonPlanned = "Planned"
+    ReasonDeletionLimitExceeded   = "DeletionLimitExceeded"
+    ReasonDeletionLimitOverridden = "DeletionLimitOverridden"
+    ReasonWithinDeletionLimit     = "WithinDeletionLimit"
+    ReasonSuspended               = "Suspended"
+    // Reasons of the Approved condition of AccessRequests
+    ReasonAwaitingApproval = "AwaitingApproval"
+    ReasonApproved         = "Approved"
+    ReasonInvalidApprover  = "InvalidApprover"
+    // ReasonApprovalNotAuthenticated is used while approvals are not trusted because the validating webhook may not be installed
+    ReasonApprovalNotAuthenticated = "ApprovalNotAuthenticated"
+    ReasonDenied           = "Denied"
+    ReasonRevoked          = "Revoked"
+    ReasonInvalidIncludes  = "InvalidIncludes"
+    ReasonRoleNotFound     = "RoleNotFound"
+    // ReasonRoleConflict is used when a Role or ClusterRole the group would render already exists without belonging to the group
+    ReasonRoleConflict = "RoleConflict"
+)
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// setReadyCondition records the outcome of a reconciliation in the Ready condition
+func setReadyCondition(conditions *[]v3.Condition, generation int64, err error) {
+	if err != nil {
+		meta.SetStatusCondition(conditions, v3.Condition{
+			Type:               v1alpha2.ConditionReady,
+			Status:             v3.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             ReasonReconcileFailed,
+			Message:            err.Error(),
+		})
+		return
+	}
+
+	meta.SetStatusCondition(conditions, v3.Condition{
+		Type:               v1alpha2.ConditionReady,
+		Status:             v3.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             ReasonReconciled,
+		Message:            "All managed resources are in sync",
+	})
+}
+
+// setDegradedCondition marks the resource as degraded as long as problems is not empty
+func setDegradedCondition(conditions *[]v3.Condition, generation int64, reason string, problems []string) {
+	if len(problems) > 0 {
+		meta.SetStatusCondition(conditions, v3.Condition{
+			Type:               v1alpha2.ConditionDegraded,
+			Status:             v3.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             reason,
+			Message:            strings.Join(problems, "; "),
+		})
+		return
+	}
+
+	meta.SetStatusCondition(conditions, v3.Condition{
+		Type:               v1alpha2.ConditionDegraded,
+		Status:             v3.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             ReasonReconciled,
+	})
+}
+
+// updateUserStatus writes the given status through the status subresource, skipping the request if nothing changed
+func (c *Controller) updateUserStatus(ctx context.Context, user *v1alpha2.User, status v1alpha2.UserStatus) error {
+	if equality.Semantic.DeepEqual(user.Status, status) {
+		return nil
+	}
+
+	updated := user.DeepCopy()
+	updated.Status = status
+	_, err := c.clientSet.Perm8sV1alpha1().Users(user.Namespace).UpdateStatus(ctx, updated, v3.UpdateOptions{FieldManager: FieldManager})
+	return err
+}
+
+// updateGroupStatus writes the given status through the status subresource, skipping the request if nothing changed
+func (c *Controller) updateGroupStatus(ctx context.Context, group *v1alpha2.Group, status v1alpha2.GroupStatus) error {
+	if equality.Semantic.DeepEqual(group.Status, status) {
+		return nil
+	}
+
+	updated := group.DeepCopy()
+	updated.Status = status
+	_, err := c.clientSet.Perm8sV1alpha1().Groups(group.Namespace).UpdateStatus(ctx, updated, v3.UpdateOptions{FieldManager: FieldManager})
+	return err
+}
+
+// updateSyncSourceStatus writes the given status through the status subresource, skipping the request if nothing changed
+func (c *Controller) updateSyncSourceStatus(ctx context.Context, source *v1alpha2.SynchronisationSource, status v1alpha2.SynchronisationSourceStatus) error {
+	if equality.Semantic.DeepEqual(source.Status, status) {
+		return nil
+	}
+
+	updated := source.DeepCopy()
+	updated.Status = status
+	_, err := c.clientSet.Perm8sV1alpha1().SynchronisationSources(source.Namespace).UpdateStatus(ctx, updated, v3.UpdateOptions{FieldManager: FieldManager})
+	return err
+}
+
+// updateAccessRequestStatus writes the given status through the status subresource, skipping the request if nothing changed
+func (c *Controller) updateAccessRequestStatus(ctx context.Context, request *v1alpha2.AccessRequest, status v1alpha2.AccessRequestStatus) error {
+	if equality.Semantic.DeepEqual(request.Status, status) {
+		return nil
+	}
+
+	updated := request.DeepCopy()
+	updated.Status = status
+	_, err := c.clientSet.Perm8sV1alpha1().AccessRequests(request.Namespace).UpdateStatus(ctx, updated, v3.UpdateOptions{FieldManager: FieldManager})
+	return err
+}
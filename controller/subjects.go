@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	v1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// groupSubjects returns the OIDC group claims the group is bound to directly. They are taken from spec.subjects and
+// from the GroupMappings of SynchronisationSources that target group subjects instead of Users.
+func (c *Controller) groupSubjects(group *v1alpha2.Group) ([]v1.Subject, error) {
+	prefix := c.options.OIDCGroupsPrefix
+	var claims []string
+
+	if group.Spec.Subjects != nil {
+		if group.Spec.Subjects.GroupsPrefix != nil {
+			prefix = *group.Spec.Subjects.GroupsPrefix
+		}
+		claims = append(claims, group.Spec.Subjects.OIDCGroups...)
+	}
+
+	sources, err := c.syncSourceLister.SynchronisationSources(group.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources {
+		if !bindsGroupSubjects(source) {
+			continue
+		}
+
+		for claim, groupName := range source.Spec.GroupMappings {
+			if groupName == group.Name && !slices.Contains(claims, claim) {
+				claims = append(claims, claim)
+			}
+		}
+	}
+
+	// the mappings are iterated in random order, which would otherwise cause needless updates of the bindings
+	slices.Sort(claims)
+	claims = slices.Compact(claims)
+
+	subjects := make([]v1.Subject, 0, len(claims))
+	for _, claim := range claims {
+		if IsReservedSubject(prefix, claim) {
+			continue
+		}
+
+		subjects = append(subjects, v1.Subject{
+			Name:     prefix + claim,
+			Kind:     v1.GroupKind,
+			APIGroup: v1.GroupName,
+		})
+	}
+
+	return subjects, nil
+}
+
+// bindsGroupSubjects reports whether the GroupMappings of the source are bound as OIDC group subjects
+func bindsGroupSubjects(source *v1alpha2.SynchronisationSource) bool {
+	return source.Spec.Target == v1alpha2.SyncTargetGroupSubjects && source.Spec.Mode != v1alpha2.SyncModePlan
+}
+
+// reconcileGroupSubjects binds the roles of the group, and of every group it includes, to the OIDC group subjects of
+// the group. The API server authenticator decides who is a member, so no Users are involved. Bindings of subjects
+// that were removed are deleted.
+func (c *Controller) reconcileGroupSubjects(ctx context.Context, group *v1alpha2.Group, status *v1alpha2.GroupStatus) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "group", group.Name)
+
+	subjects, err := c.groupSubjects(group)
+	if err != nil {
+		return err
+	}
+
+	if len(subjects) > 0 {
+		includedGroups, _ := c.expandGroups(group.Namespace, []string{group.Name})
+
+		for i, groupName := range includedGroups {
+			target := group
+			name := fmt.Sprintf("%v:subjects", group.Name)
+
+			if i > 0 {
+				target, err = c.groupLister.Groups(group.Namespace).Get(groupName)
+				if errors.IsNotFound(err) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				if target.DeletionTimestamp != nil {
+					continue
+				}
+				name = fmt.Sprintf("%v:subjects:%v", group.Name, groupName)
+			}
+
+			clusterRoleBindings, roleBindings, err := c.groupBindings(target, bindingTemplate{
+				name:     name,
+				labels:   groupLabels(group),
+				subjects: subjects,
+				owner:    v3.NewControllerRef(group, v1alpha2.SchemeGroupVersion.WithKind("Group")),
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, clusterRoleBinding := range clusterRoleBindings {
+				if err = c.ensureClusterRoleBinding(ctx, group, clusterRoleBinding); err != nil {
+					logger.Error(err, "Error while syncing ClusterRoleBinding of group subjects", "clusterRoleBinding", clusterRoleBinding.Name)
+					return err
+				}
+				status.SubjectBindings = append(status.SubjectBindings, clusterRoleBinding.Name)
+			}
+
+			for _, roleBinding := range roleBindings {
+				if err = c.ensureRoleBinding(ctx, group, roleBinding); err != nil {
+					logger.Error(err, "Error while syncing RoleBinding of group subjects", "namespace", roleBinding.Namespace, "roleBinding", roleBinding.Name)
+					return err
+				}
+				status.SubjectBindings = append(status.SubjectBindings, fmt.Sprintf("%v/%v", roleBinding.Namespace, roleBinding.Name))
+			}
+		}
+	}
+
+	// bindings of users carry the user label as well, they are cleaned up by the user controller
+	selector := v3.ListOptions{LabelSelector: fmt.Sprintf("%v=%v,%v=%v,!%v", LabelGroup, group.Name, LabelNamespace, group.Namespace, LabelUser)}
+
+	roleBindings, err := c.kubeclientset.RbacV1().RoleBindings("").List(ctx, selector)
+	if err != nil {
+		return err
+	}
+
+	for _, roleBinding := range roleBindings.Items {
+		if slices.Contains(status.SubjectBindings, fmt.Sprintf("%v/%v", roleBinding.Namespace, roleBinding.Name)) {
+			continue
+		}
+
+		logger.Info("Removing dangling RoleBinding of group subjects", "namespace", roleBinding.Namespace, "roleBinding", roleBinding.Name)
+		err = c.kubeclientset.RbacV1().RoleBindings(roleBinding.Namespace).Delete(ctx, roleBinding.Name, v3.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	clusterRoleBindings, err := c.kubeclientset.RbacV1().ClusterRoleBindings().List(ctx, selector)
+	if err != nil {
+		return err
+	}
+
+	for _, clusterRoleBinding := range clusterRoleBindings.Items {
+		if slices.Contains(status.SubjectBindings, clusterRoleBinding.Name) {
+			continue
+		}
+
+		logger.Info("Removing dangling ClusterRoleBinding of group subjects", "clusterRoleBinding", clusterRoleBinding.Name)
+		err = c.kubeclientset.RbacV1().ClusterRoleBindings().Delete(ctx, clusterRoleBinding.Name, v3.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enqueueGroupsOfSyncSource enqueues every group that is mapped by a SynchronisationSource, so that the group subjects
+// follow changes of its GroupMappings and target
+func (c *Controller) enqueueGroupsOfSyncSource(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	source, ok := obj.(*v1alpha2.SynchronisationSource)
+	if !ok {
+		return
+	}
+
+	for _, groupName := range source.Spec.GroupMappings {
+		if groupName == "" {
+			continue
+		}
+		c.groupWorkqueue.Add(cache.ObjectName{Namespace: source.Namespace, Name: groupName})
+	}
+}
+
+// groupSubjectsChanged reports whether an update of a SynchronisationSource could change the subjects of its groups
+func groupSubjectsChanged(old, new interface{}) bool {
+	oldSource, ok := old.(*v1alpha2.SynchronisationSource)
+	if !ok {
+		return true
+	}
+
+	newSource, ok := new.(*v1alpha2.SynchronisationSource)
+	if !ok {
+		return true
+	}
+
+	if !bindsGroupSubjects(oldSource) && !bindsGroupSubjects(newSource) {
+		return false
+	}
+
+	if bindsGroupSubjects(oldSource) != bindsGroupSubjects(newSource) {
+		return true
+	}
+
+	for claim, groupName := range oldSource.Spec.GroupMappings {
+		if newGroupName, ok := newSource.Spec.GroupMappings[claim]; !ok || newGroupName != groupName {
+			return true
+		}
+	}
+
+	return len(oldSource.Spec.GroupMappings) != len(newSource.Spec.GroupMappings)
+}
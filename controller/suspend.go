@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	v2 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// suspendUser removes all access of a suspended user, which are its bindings, its ServiceAccount and its Secrets.
+// Deleting the ServiceAccount revokes every token issued for it, including the ones of the TokenRequest API which cannot
+// be revoked individually. Everything is recreated once the user is no longer suspended.
+func (c *Controller) suspendUser(ctx context.Context, user *v1alpha2.User) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "user", user.Name)
+	selector := v3.ListOptions{LabelSelector: fmt.Sprintf("%v=%v,%v=%v", LabelUser, user.Name, LabelNamespace, user.Namespace)}
+	removed := 0
+
+	roleBindings, err := c.kubeclientset.RbacV1().RoleBindings("").List(ctx, selector)
+	if err != nil {
+		return err
+	}
+
+	for _, roleBinding := range roleBindings.Items {
+		logger.Info("Deleting RoleBinding of suspended user", "namespace", roleBinding.Namespace, "roleBinding", roleBinding.Name)
+		err = c.kubeclientset.RbacV1().RoleBindings(roleBinding.Namespace).Delete(ctx, roleBinding.Name, v3.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		removed++
+	}
+
+	clusterRoleBindings, err := c.kubeclientset.RbacV1().ClusterRoleBindings().List(ctx, selector)
+	if err != nil {
+		return err
+	}
+
+	for _, clusterRoleBinding := range clusterRoleBindings.Items {
+		logger.Info("Deleting ClusterRoleBinding of suspended user", "clusterRoleBinding", clusterRoleBinding.Name)
+		err = c.kubeclientset.RbacV1().ClusterRoleBindings().Delete(ctx, clusterRoleBinding.Name, v3.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		removed++
+	}
+
+	removedCredentials, err := c.removeServiceAccount(ctx, user)
+	if err != nil {
+		return err
+	}
+	removed += removedCredentials
+
+	if removed > 0 {
+		c.recorder.Event(user, v2.EventTypeNormal, ReasonSuspended, "User is suspended, removed all bindings and revoked its tokens")
+	}
+
+	return nil
+}
+
+// removeServiceAccount deletes the ServiceAccount and Secrets of the user and returns how many objects were deleted.
+// Deleting the ServiceAccount revokes every token issued for it.
+func (c *Controller) removeServiceAccount(ctx context.Context, user *v1alpha2.User) (int, error) {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "user", user.Name)
+	selector := v3.ListOptions{LabelSelector: fmt.Sprintf("%v=%v,%v=%v", LabelUser, user.Name, LabelNamespace, user.Namespace)}
+	removed := 0
+
+	err := c.apiClient.ServiceAccounts(user.Namespace).Delete(ctx, c.ServiceAccountFromUser(user).Name, v3.DeleteOptions{})
+	if err == nil {
+		logger.Info("Deleted ServiceAccount of user, all of its tokens are revoked")
+		removed++
+	} else if !errors.IsNotFound(err) {
+		return removed, err
+	}
+
+	secrets, err := c.apiClient.Secrets(user.Namespace).List(ctx, selector)
+	if err != nil {
+		return removed, err
+	}
+
+	for _, secret := range secrets.Items {
+		logger.Info("Deleting Secret of user", "secret", secret.Name)
+		err = c.apiClient.Secrets(user.Namespace).Delete(ctx, secret.Name, v3.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
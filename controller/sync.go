@@ -2,21 +2,37 @@ package controller
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
 	v2 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
 	"perm8s/sync"
-	"reflect"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
 )
 
 var nonAlphanumericRegex = regexp.MustCompile(`[^a-zA-Z0-9 ]+`)
 
+const defaultSyncInterval = 5 * time.Minute
+
+var (
+	// errSourceUnavailable wraps errors that originate from the upstream synchronisation source rather than the cluster
+	errSourceUnavailable = stderrors.New("synchronisation source unavailable")
+	// errInvalidSpec wraps errors caused by a SynchronisationSource that cannot be processed as configured
+	errInvalidSpec = stderrors.New("invalid synchronisation source")
+	// errDeletionLimitExceeded is returned when a synchronisation was aborted because it would delete too many Users
+	errDeletionLimitExceeded = stderrors.New("deletion limit exceeded")
+)
+
 func (c *Controller) enqueueSyncSource(obj interface{}) {
 	if objectRef, err := cache.ObjectToName(obj); err != nil {
 		utilruntime.HandleError(err)
@@ -40,9 +56,13 @@ func (c *Controller) processNextSyncWorkItem(ctx context.Context) bool {
 	}
 
 	defer c.syncSourceWorkqueue.Done(objRef)
+	defer c.heartbeats.begin(kindSyncSource)()
 
 	// Run the syncHandler, passing it the structured reference to the object to be synced.
+	start := time.Now()
 	err := c.syncSyncHandler(ctx, objRef.(cache.ObjectName))
+	observeReconcile(kindSyncSource, start, err)
+
 	if err == nil {
 
 		c.syncSourceWorkqueue.Forget(objRef)
@@ -63,112 +83,205 @@ func (c *Controller) syncSyncHandler(ctx context.Context, objectRef cache.Object
 	if err != nil {
 		if errors.IsNotFound(err) {
 			logger.Error(err, "SynchronisationSource in workqueue no longer exists")
+			forgetSyncSourceMetrics(objectRef.Namespace, objectRef.Name)
 			return nil
 		}
 
 		return err
 	}
 
-	computeFunc, ok := sync.SyncSources[source.Spec.Type]
+	now := time.Now()
+	nextSync, scheduleErr := nextSyncTime(source, lastAttemptTime(source))
 
-	if !ok {
-		logger.Error(err, "Cannot find synchronisation source type", "type", source.Spec.Type)
-		c.recorder.Event(source, v2.EventTypeWarning, "Failed", "Cannot find synchronisation source type "+source.Spec.Type)
+	// a failed synchronisation was requeued by the rate limiter and is retried with its backoff instead of the interval
+	retrying := c.syncSourceWorkqueue.NumRequeues(objectRef) > 0
+
+	if scheduleErr == nil && !retrying && !syncRequested(source) && now.Before(nextSync) {
+		logger.V(4).Info("Synchronisation interval has not elapsed yet, skipping", "nextSync", nextSync)
+		c.syncSourceWorkqueue.AddAfter(objectRef, nextSync.Sub(now))
 		return nil
 	}
 
-	logger = logger.WithValues("sourceType", source.Spec.Type)
+	status := *source.Status.DeepCopy()
+	status.ObservedGeneration = source.Generation
+	status.LastAttemptTime = &v3.Time{Time: now}
+	status.LastHandledSyncRequest = source.Annotations[v1alpha2.SyncNowAnnotation]
 
-	users, err := computeFunc(ctx, *source, c.apiClient)
-
-	if err != nil {
-		logger.Error(err, "Error while computing users", "type", source.Spec.Type)
-		c.recorder.Event(source, v2.EventTypeWarning, "Failed", "Error while computing users")
-		return nil
+	if scheduleErr != nil {
+		logger.Error(scheduleErr, "Invalid synchronisation schedule", "schedule", source.Spec.Schedule)
+		c.recorder.Event(source, v2.EventTypeWarning, "Failed", "Invalid synchronisation schedule "+source.Spec.Schedule)
+		err = fmt.Errorf("%w: invalid schedule %q: %w", errInvalidSpec, source.Spec.Schedule, scheduleErr)
+	} else {
+		err = c.reconcileSyncSource(ctx, source, &status)
 	}
 
-	for _, user := range *users {
-		identifier := GetIdentifier(user.Name)
+	setReadyCondition(&status.Conditions, source.Generation, err)
 
-		var groups []string
+	if err != nil {
+		status.LastError = err.Error()
+	} else {
+		now := v3.Now()
+		status.LastSyncTime = &now
+		status.LastError = ""
+		syncSourceLastSuccess.WithLabelValues(source.Namespace, source.Name).Set(float64(now.Unix()))
+	}
 
-		for _, g := range user.Groups {
-			if groupName, ok := source.Spec.GroupMappings[g]; ok {
-				groups = append(groups, groupName)
-			}
+	if statusErr := c.updateSyncSourceStatus(ctx, source, status); statusErr != nil {
+		logger.Error(statusErr, "Error while updating SynchronisationSource status", "source", source.Name)
+		if err == nil {
+			return statusErr
 		}
+	}
 
-		if source.Spec.DefaultGroups != nil {
-			groups = append(groups, *source.Spec.DefaultGroups...)
+	if scheduleErr == nil {
+		if nextSync, err := nextSyncTime(source, now); err == nil {
+			c.syncSourceWorkqueue.AddAfter(objectRef, nextSync.Sub(now))
 		}
+	}
 
-		desiredUser := c.GetUserFromSyncUser(identifier, user.Name, source.Namespace, groups, source)
-
-		currentUser, err := c.clientSet.Perm8sV1alpha1().Users(source.Namespace).Get(ctx, desiredUser.Name, v3.GetOptions{})
+	// errors of the upstream source or the configuration are reported through the status and do not cause an immediate retry
+	if stderrors.Is(err, errSourceUnavailable) || stderrors.Is(err, errInvalidSpec) || stderrors.Is(err, errDeletionLimitExceeded) {
+		return nil
+	}
 
-		if errors.IsNotFound(err) {
-			logger.Info("User account does not exist for external identity user yet, creating new")
-			_, err := c.clientSet.Perm8sV1alpha1().Users(source.Namespace).Create(ctx, desiredUser, v3.CreateOptions{})
-
-			if err != nil {
-				return err
-			}
-			c.recorder.Event(source, v2.EventTypeNormal, SuccessSynced, "User Account created for external users")
-			continue
-		}
+	return err
+}
 
+// nextSyncTime returns the time the given source is due to be synchronised again after a synchronisation at last
+func nextSyncTime(source *v1alpha2.SynchronisationSource, last time.Time) (time.Time, error) {
+	if source.Spec.Schedule != "" {
+		schedule, err := cron.ParseStandard(source.Spec.Schedule)
 		if err != nil {
-			return err
+			return time.Time{}, err
 		}
 
-		if !reflect.DeepEqual(currentUser.Spec, desiredUser.Spec) {
-			logger.Info("External User is out of sync, resynching")
-			desiredUser.SetResourceVersion(currentUser.GetResourceVersion())
-			_, err = c.clientSet.Perm8sV1alpha1().Users(source.Namespace).Update(ctx, desiredUser, v3.UpdateOptions{})
+		return schedule.Next(last), nil
+	}
 
-			if err != nil {
-				return err
-			}
-		}
+	interval := defaultSyncInterval
+	if source.Spec.SyncInterval != nil && source.Spec.SyncInterval.Duration > 0 {
+		interval = source.Spec.SyncInterval.Duration
+	}
+
+	return last.Add(interval), nil
+}
+
+func lastAttemptTime(source *v1alpha2.SynchronisationSource) time.Time {
+	if source.Status.LastAttemptTime == nil {
+		return time.Time{}
+	}
+
+	return source.Status.LastAttemptTime.Time
+}
+
+// syncRequested reports whether the source has to be synchronised regardless of its interval, either because its spec
+// changed, because a new sync-now annotation value was set or because a synchronisation that was aborted by the deletion
+// limit was overridden. An override that was not needed yet stays pending without bypassing the interval
+func syncRequested(source *v1alpha2.SynchronisationSource) bool {
+	return source.Generation != source.Status.ObservedGeneration ||
+		source.Annotations[v1alpha2.SyncNowAnnotation] != source.Status.LastHandledSyncRequest ||
+		(deletionOverrideRequested(source) && meta.IsStatusConditionTrue(source.Status.Conditions, v1alpha2.ConditionDeletionLimitExceeded))
+}
+
+// reconcileSyncSource fetches all users from the upstream source and creates, updates or deletes the corresponding Users
+func (c *Controller) reconcileSyncSource(ctx context.Context, source *v1alpha2.SynchronisationSource, status *v1alpha2.SynchronisationSourceStatus) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "source", source.Name)
+
+	computeFunc, ok := sync.SyncSources[source.Spec.Type]
+
+	if !ok {
+		logger.Error(nil, "Cannot find synchronisation source type", "type", source.Spec.Type)
+		c.recorder.Event(source, v2.EventTypeWarning, "Failed", "Cannot find synchronisation source type "+source.Spec.Type)
+		return fmt.Errorf("%w: cannot find synchronisation source type %v", errInvalidSpec, source.Spec.Type)
 	}
 
-	// finally, we need to make sure no users exist that are not part of the target group anymore
-	result, err := c.clientSet.Perm8sV1alpha1().Users(source.Namespace).List(ctx, v3.ListOptions{})
+	logger = logger.WithValues("sourceType", source.Spec.Type)
 
+	users, err := c.fetchSyncUsers(ctx, source, computeFunc, status)
 	if err != nil {
 		return err
 	}
 
-	for _, user := range result.Items {
-		if user.Spec.AuthenticationSource != source.Name {
-			continue
-		}
-		found := false
-		for _, correctUser := range *users {
-			if GetIdentifier(correctUser.Name) == user.Name {
-				found = true
-				break
-			}
-		}
+	status.UserCount = len(*users)
+	syncSourceUsers.WithLabelValues(source.Namespace, source.Name).Set(float64(len(*users)))
 
-		if !found {
-			logger.Info("User is orphaned and will be deleted", "user", user.Name, "namespace", user.Namespace)
+	plan, err := c.planSyncSource(ctx, source, *users)
+	if err != nil {
+		return err
+	}
 
-			err = c.clientSet.Perm8sV1alpha1().Users(user.Namespace).Delete(ctx, user.Name, v3.DeleteOptions{})
+	setDegradedCondition(&status.Conditions, source.Generation, ReasonInvalidIdentifier, plan.invalid)
+	if len(plan.invalid) > 0 {
+		c.recorder.Event(source, v2.EventTypeWarning, ReasonInvalidIdentifier, fmt.Sprintf("Skipped %d users that cannot be stored as a valid User", len(plan.invalid)))
+	}
 
-			if err != nil {
-				logger.Error(err, "Error during deletion of orphaned User", "user", user.Name, "namespace", user.Namespace)
-				return err
-			}
+	overridden, limitErr := c.checkDeletionLimit(source, plan, status)
 
-			logger.Info("Orphaned user deleted successfully", "user", user.Name, "namespace", user.Namespace)
-		}
+	if source.Spec.Mode == v1alpha2.SyncModePlan {
+		logger.Info("Source is in plan mode, not applying changes", "create", len(plan.create), "update", len(plan.update), "delete", len(plan.delete))
+		status.Plan = &plan.summary
+		c.recorder.Event(source, v2.EventTypeNormal, ReasonPlanned, plan.describe())
+		return nil
+	}
+
+	status.Plan = nil
+	if limitErr != nil {
+		logger.Info("Synchronisation would delete too many Users, aborting", "delete", len(plan.delete))
+		c.recorder.Event(source, v2.EventTypeWarning, ReasonDeletionLimitExceeded, limitErr.Error()+", set the "+v1alpha2.AllowMassDeletionAnnotation+" annotation to proceed")
+		return limitErr
+	}
+
+	if err = c.applySyncPlan(ctx, source, plan); err != nil {
+		return err
+	}
+
+	// the override is only used up by a synchronisation that needed it and completed, a failed one can be retried with it
+	if overridden {
+		status.LastHandledDeletionOverride = source.Annotations[v1alpha2.AllowMassDeletionAnnotation]
 	}
 
 	c.recorder.Event(source, v2.EventTypeNormal, SuccessSynced, "Synchronisation Source has been synced successfully")
 	return nil
 }
 
+// fetchSyncUsers returns the users of the upstream source and records whether it was reachable. Sources that target
+// group subjects do not provision Users, so nothing is fetched and Users created earlier are deprovisioned.
+func (c *Controller) fetchSyncUsers(ctx context.Context, source *v1alpha2.SynchronisationSource, computeFunc sync.ComputeUserFunc, status *v1alpha2.SynchronisationSourceStatus) (*[]sync.SyncUser, error) {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "source", source.Name, "sourceType", source.Spec.Type)
+
+	if source.Spec.Target == v1alpha2.SyncTargetGroupSubjects {
+		meta.RemoveStatusCondition(&status.Conditions, v1alpha2.ConditionSourceReachable)
+		return &[]sync.SyncUser{}, nil
+	}
+
+	fetchStart := time.Now()
+	users, err := computeFunc(ctx, *source, c.apiClient)
+	syncSourceFetchDuration.WithLabelValues(source.Namespace, source.Name, source.Spec.Type).Observe(time.Since(fetchStart).Seconds())
+
+	if err != nil {
+		logger.Error(err, "Error while computing users", "type", source.Spec.Type)
+		c.recorder.Event(source, v2.EventTypeWarning, "Failed", "Error while computing users")
+		meta.SetStatusCondition(&status.Conditions, v3.Condition{
+			Type:               v1alpha2.ConditionSourceReachable,
+			Status:             v3.ConditionFalse,
+			ObservedGeneration: source.Generation,
+			Reason:             ReasonSourceError,
+			Message:            err.Error(),
+		})
+		return nil, fmt.Errorf("%w: %w", errSourceUnavailable, err)
+	}
+
+	meta.SetStatusCondition(&status.Conditions, v3.Condition{
+		Type:               v1alpha2.ConditionSourceReachable,
+		Status:             v3.ConditionTrue,
+		ObservedGeneration: source.Generation,
+		Reason:             ReasonSourceReachable,
+		Message:            fmt.Sprintf("Source returned %d users", len(*users)),
+	})
+
+	return users, nil
+}
+
 func GetIdentifier(accountName string) string {
 	return nonAlphanumericRegex.ReplaceAllString(strings.ReplaceAll(strings.TrimSpace(strings.ToLower(accountName)), " ", "-"), "")
 }
@@ -0,0 +1,241 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v2 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+const (
+	// AnnotationTokenIssuedAt and AnnotationTokenExpiration record the lifetime of a token issued through the TokenRequest API
+	AnnotationTokenIssuedAt   = "perm8s.tobiasgrether.com/token-issued-at"
+	AnnotationTokenExpiration = "perm8s.tobiasgrether.com/token-expiration"
+	// AnnotationTokenRequest records the TTL and audiences a token was requested with, so that a token is reissued when they change
+	AnnotationTokenRequest = "perm8s.tobiasgrether.com/token-request"
+
+	// rootCAConfigMap is published into every namespace by the kube-controller-manager
+	rootCAConfigMap = "kube-root-ca.crt"
+	// tokenRefreshRatio is the share of the token lifetime after which a token is refreshed
+	tokenRefreshRatio = 0.8
+)
+
+// tokenSpecForUser returns the token configuration of the user, completed with the defaults of the controller
+func (c *Controller) tokenSpecForUser(user *v1alpha2.User) v1alpha2.UserTokenSpec {
+	spec := v1alpha2.UserTokenSpec{
+		Mode:      c.options.TokenMode,
+		TTL:       &v3.Duration{Duration: c.options.TokenTTL},
+		Audiences: c.options.TokenAudiences,
+	}
+
+	if user.Spec.Token != nil {
+		if user.Spec.Token.Mode != "" {
+			spec.Mode = user.Spec.Token.Mode
+		}
+		if user.Spec.Token.TTL != nil {
+			spec.TTL = user.Spec.Token.TTL
+		}
+		if len(user.Spec.Token.Audiences) > 0 {
+			spec.Audiences = user.Spec.Token.Audiences
+		}
+	}
+
+	if spec.Mode == "" {
+		spec.Mode = v1alpha2.TokenModeLegacy
+	}
+
+	return spec
+}
+
+// reconcileUserToken makes sure the credential Secret of the user holds a valid token and returns it
+func (c *Controller) reconcileUserToken(ctx context.Context, user *v1alpha2.User, serviceAccount *v2.ServiceAccount, status *v1alpha2.UserStatus) (*v2.Secret, error) {
+	tokenSpec := c.tokenSpecForUser(user)
+
+	if tokenSpec.Mode == v1alpha2.TokenModeTokenRequest {
+		return c.reconcileRequestedToken(ctx, user, serviceAccount, tokenSpec, status)
+	}
+
+	status.TokenExpirationTime = nil
+	return c.reconcileLegacyToken(ctx, user, serviceAccount)
+}
+
+func (c *Controller) reconcileLegacyToken(ctx context.Context, user *v1alpha2.User, serviceAccount *v2.ServiceAccount) (*v2.Secret, error) {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "user", user.Name, "serviceAccount", serviceAccount.Name)
+	desiredSecret := c.AuthenticationSecretFromServiceAccount(serviceAccount, user)
+
+	secret, err := c.apiClient.Secrets(user.Namespace).Get(ctx, desiredSecret.Name, v3.GetOptions{})
+
+	if err == nil && secret.Type != v2.SecretTypeServiceAccountToken {
+		// the secret type is immutable, so a secret left over from the tokenRequest mode has to be replaced
+		logger.Info("Authentication secret has been issued in a different token mode, replacing", "secret", secret.Name)
+		if err = c.apiClient.Secrets(user.Namespace).Delete(ctx, secret.Name, v3.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		err = errors.NewNotFound(v2.Resource("secrets"), secret.Name)
+	}
+
+	if errors.IsNotFound(err) {
+		logger.Info("No token secret exists for user, creating secret")
+		return c.apiClient.Secrets(serviceAccount.Namespace).Create(ctx, desiredSecret, v3.CreateOptions{FieldManager: FieldManager})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	desiredLabels, labelsChanged := mergeMetadata(secret.Labels, desiredSecret.Labels)
+	desiredAnnotations, annotationsChanged := mergeMetadata(secret.Annotations, desiredSecret.Annotations)
+
+	if labelsChanged || annotationsChanged {
+		logger.Info("Authentication secret is out of sync, resyncing", "secret", secret.Name)
+		secret = secret.DeepCopy()
+		secret.Labels = desiredLabels
+		secret.Annotations = desiredAnnotations
+
+		return c.apiClient.Secrets(user.Namespace).Update(ctx, secret, v3.UpdateOptions{FieldManager: FieldManager})
+	}
+
+	return secret, nil
+}
+
+// reconcileRequestedToken mints a token through the TokenRequest API whenever the stored token is missing, was requested
+// with different parameters or has used up most of its lifetime, and schedules the user for the next refresh
+func (c *Controller) reconcileRequestedToken(ctx context.Context, user *v1alpha2.User, serviceAccount *v2.ServiceAccount, tokenSpec v1alpha2.UserTokenSpec, status *v1alpha2.UserStatus) (*v2.Secret, error) {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "user", user.Name, "serviceAccount", serviceAccount.Name)
+	secretName := fmt.Sprintf("%v-usertoken", serviceAccount.Name)
+	requestDescription := tokenRequestDescription(tokenSpec)
+	now := time.Now()
+
+	secret, err := c.apiClient.Secrets(user.Namespace).Get(ctx, secretName, v3.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if err == nil && secret.Type == v2.SecretTypeServiceAccountToken {
+		// deleting the legacy secret makes the token controller revoke the non-expiring token
+		logger.Info("Removing legacy token secret of user, switching to TokenRequest mode", "secret", secret.Name)
+		if err = c.apiClient.Secrets(user.Namespace).Delete(ctx, secret.Name, v3.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		secret = nil
+	} else if errors.IsNotFound(err) {
+		secret = nil
+	}
+
+	if secret != nil && secret.Annotations[AnnotationTokenRequest] == requestDescription && len(secret.Data["token"]) > 0 {
+		refreshAt, expiresAt, ok := tokenRefreshTime(secret)
+		if ok && now.Before(refreshAt) {
+			status.TokenExpirationTime = &v3.Time{Time: expiresAt}
+			c.userWorkqueue.AddAfter(cache.ObjectName{Namespace: user.Namespace, Name: user.Name}, refreshAt.Sub(now))
+
+			desiredLabels, labelsChanged := mergeMetadata(secret.Labels, userLabels(user))
+			if !labelsChanged {
+				return secret, nil
+			}
+
+			secret = secret.DeepCopy()
+			secret.Labels = desiredLabels
+			return c.apiClient.Secrets(user.Namespace).Update(ctx, secret, v3.UpdateOptions{FieldManager: FieldManager})
+		}
+	}
+
+	logger.Info("Requesting new token for user")
+	expirationSeconds := int64(tokenSpec.TTL.Duration.Seconds())
+	tokenRequest, err := c.apiClient.ServiceAccounts(serviceAccount.Namespace).CreateToken(ctx, serviceAccount.Name, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         tokenSpec.Audiences,
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}, v3.CreateOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	caData := []byte{}
+	rootCA, err := c.apiClient.ConfigMaps(user.Namespace).Get(ctx, rootCAConfigMap, v3.GetOptions{})
+	if err != nil {
+		logger.Error(err, "Cannot read cluster CA bundle, token secret will not contain a CA", "configMap", rootCAConfigMap)
+	} else {
+		caData = []byte(rootCA.Data["ca.crt"])
+	}
+
+	expiresAt := tokenRequest.Status.ExpirationTimestamp.Time
+	desiredSecret := c.RequestedTokenSecretForUser(user, secretName, tokenRequest.Status.Token, caData, now, expiresAt, requestDescription)
+
+	if secret == nil {
+		secret, err = c.apiClient.Secrets(user.Namespace).Create(ctx, desiredSecret, v3.CreateOptions{FieldManager: FieldManager})
+	} else {
+		updated := secret.DeepCopy()
+		updated.Labels, _ = mergeMetadata(secret.Labels, desiredSecret.Labels)
+		updated.Annotations, _ = mergeMetadata(secret.Annotations, desiredSecret.Annotations)
+		updated.Data = desiredSecret.Data
+		secret, err = c.apiClient.Secrets(user.Namespace).Update(ctx, updated, v3.UpdateOptions{FieldManager: FieldManager})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.recorder.Event(user, v2.EventTypeNormal, SuccessCreated, "Issued new token valid until "+expiresAt.Format(time.RFC3339))
+	status.TokenExpirationTime = &v3.Time{Time: expiresAt}
+
+	refreshAt, _, _ := tokenRefreshTime(secret)
+	c.userWorkqueue.AddAfter(cache.ObjectName{Namespace: user.Namespace, Name: user.Name}, refreshAt.Sub(now))
+
+	return secret, nil
+}
+
+func (c *Controller) RequestedTokenSecretForUser(user *v1alpha2.User, name string, token string, caData []byte, issuedAt time.Time, expiresAt time.Time, requestDescription string) *v2.Secret {
+	return &v2.Secret{
+		Type: v2.SecretTypeOpaque,
+		ObjectMeta: v3.ObjectMeta{
+			Name:      name,
+			Namespace: user.Namespace,
+			Labels:    userLabels(user),
+			OwnerReferences: []v3.OwnerReference{
+				*v3.NewControllerRef(user, v1alpha2.SchemeGroupVersion.WithKind("User")),
+			},
+			Annotations: map[string]string{
+				AnnotationTokenIssuedAt:   issuedAt.UTC().Format(time.RFC3339),
+				AnnotationTokenExpiration: expiresAt.UTC().Format(time.RFC3339),
+				AnnotationTokenRequest:    requestDescription,
+			},
+		},
+		Data: map[string][]byte{
+			"token":  []byte(token),
+			"ca.crt": caData,
+		},
+	}
+}
+
+// tokenRefreshTime returns when the token stored in the given secret has to be refreshed and when it expires
+func tokenRefreshTime(secret *v2.Secret) (time.Time, time.Time, bool) {
+	issuedAt, err := time.Parse(time.RFC3339, secret.Annotations[AnnotationTokenIssuedAt])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, secret.Annotations[AnnotationTokenExpiration])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	lifetime := expiresAt.Sub(issuedAt)
+	return issuedAt.Add(time.Duration(float64(lifetime) * tokenRefreshRatio)), expiresAt, true
+}
+
+func tokenRequestDescription(tokenSpec v1alpha2.UserTokenSpec) string {
+	audiences := slices.Clone(tokenSpec.Audiences)
+	slices.Sort(audiences)
+	return fmt.Sprintf("ttl=%v;audiences=%v", tokenSpec.TTL.Duration, strings.Join(audiences, ","))
+}
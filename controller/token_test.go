@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v2 "k8s.io/api/core/v1"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// newTokenController returns a Controller whose TokenRequests are answered with a token named after the number of
+// tokens issued so far, and a pointer to that number
+func newTokenController(t *testing.T, mode string, objects ...runtime.Object) (*Controller, *fake.Clientset, *int) {
+	t.Helper()
+
+	kubeClient := fake.NewSimpleClientset(objects...)
+	issued := 0
+	kubeClient.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+
+		issued++
+		request := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest).DeepCopy()
+		request.Status = authenticationv1.TokenRequestStatus{
+			Token:               fmt.Sprintf("token-%v", issued),
+			ExpirationTimestamp: v3.NewTime(time.Now().Add(time.Duration(*request.Spec.ExpirationSeconds) * time.Second)),
+		}
+		return true, request, nil
+	})
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	t.Cleanup(queue.ShutDown)
+
+	return &Controller{
+		apiClient:     kubeClient.CoreV1(),
+		recorder:      record.NewFakeRecorder(10),
+		userWorkqueue: queue,
+		options:       Options{TokenMode: mode, TokenTTL: 24 * time.Hour},
+	}, kubeClient, &issued
+}
+
+// requestedTokenSecret returns the secret of a token that was requested with the default TTL of the tests
+func requestedTokenSecret(user *v1alpha2.User, issuedAt time.Time, ttl time.Duration) *v2.Secret {
+	c := &Controller{}
+	spec := v1alpha2.UserTokenSpec{TTL: &v3.Duration{Duration: 24 * time.Hour}}
+	return c.RequestedTokenSecretForUser(user, "jane-usertoken", "token-0", nil, issuedAt, issuedAt.Add(ttl), tokenRequestDescription(spec))
+}
+
+func TestReconcileUserToken(t *testing.T) {
+	user := &v1alpha2.User{ObjectMeta: v3.ObjectMeta{Name: "jane", Namespace: "team", UID: "user-uid"}}
+	serviceAccount := &v2.ServiceAccount{ObjectMeta: v3.ObjectMeta{Name: "jane", Namespace: "team"}}
+	now := time.Now()
+
+	legacySecret := (&Controller{}).AuthenticationSecretFromServiceAccount(serviceAccount, user)
+	changedTTL := requestedTokenSecret(user, now.Add(-time.Hour), 24*time.Hour)
+	changedTTL.Annotations[AnnotationTokenRequest] = tokenRequestDescription(v1alpha2.UserTokenSpec{TTL: &v3.Duration{Duration: time.Hour}})
+
+	tests := []struct {
+		name       string
+		mode       string
+		existing   *v2.Secret
+		wantIssued int
+		wantToken  string
+		wantType   v2.SecretType
+	}{
+		{name: "new token", mode: v1alpha2.TokenModeTokenRequest, wantIssued: 1, wantToken: "token-1", wantType: v2.SecretTypeOpaque},
+		{name: "token within its lifetime", mode: v1alpha2.TokenModeTokenRequest, existing: requestedTokenSecret(user, now.Add(-time.Hour), 24*time.Hour), wantToken: "token-0", wantType: v2.SecretTypeOpaque},
+		{name: "token due for renewal", mode: v1alpha2.TokenModeTokenRequest, existing: requestedTokenSecret(user, now.Add(-20*time.Hour), 24*time.Hour), wantIssued: 1, wantToken: "token-1", wantType: v2.SecretTypeOpaque},
+		{name: "expired token", mode: v1alpha2.TokenModeTokenRequest, existing: requestedTokenSecret(user, now.Add(-48*time.Hour), 24*time.Hour), wantIssued: 1, wantToken: "token-1", wantType: v2.SecretTypeOpaque},
+		{name: "token requested with a different TTL", mode: v1alpha2.TokenModeTokenRequest, existing: changedTTL, wantIssued: 1, wantToken: "token-1", wantType: v2.SecretTypeOpaque},
+		{name: "switching from legacy to TokenRequest", mode: v1alpha2.TokenModeTokenRequest, existing: legacySecret, wantIssued: 1, wantToken: "token-1", wantType: v2.SecretTypeOpaque},
+		{name: "switching from TokenRequest to legacy", mode: v1alpha2.TokenModeLegacy, existing: requestedTokenSecret(user, now.Add(-time.Hour), 24*time.Hour), wantType: v2.SecretTypeServiceAccountToken},
+		{name: "legacy token", mode: v1alpha2.TokenModeLegacy, existing: legacySecret, wantType: v2.SecretTypeServiceAccountToken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var objects []runtime.Object
+			if test.existing != nil {
+				objects = append(objects, test.existing.DeepCopy())
+			}
+			c, kubeClient, issued := newTokenController(t, test.mode, objects...)
+
+			status := v1alpha2.UserStatus{TokenExpirationTime: &v3.Time{Time: now}}
+			if _, err := c.reconcileUserToken(context.Background(), user, serviceAccount, &status); err != nil {
+				t.Fatalf("reconcileUserToken() error = %v", err)
+			}
+
+			if *issued != test.wantIssued {
+				t.Errorf("issued %v tokens, want %v", *issued, test.wantIssued)
+			}
+
+			secret, err := kubeClient.CoreV1().Secrets("team").Get(context.Background(), "jane-usertoken", v3.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if secret.Type != test.wantType {
+				t.Errorf("secret type = %v, want %v", secret.Type, test.wantType)
+			}
+			if token := string(secret.Data["token"]); token != test.wantToken {
+				t.Errorf("token = %q, want %q", token, test.wantToken)
+			}
+
+			if test.mode == v1alpha2.TokenModeLegacy {
+				if status.TokenExpirationTime != nil {
+					t.Errorf("TokenExpirationTime = %v, want none for legacy tokens", status.TokenExpirationTime)
+				}
+				return
+			}
+
+			// the annotations of the secret only keep whole seconds
+			refreshAt, expiresAt, ok := tokenRefreshTime(secret)
+			if !ok || status.TokenExpirationTime == nil || !status.TokenExpirationTime.Time.Truncate(time.Second).Equal(expiresAt) {
+				t.Errorf("TokenExpirationTime = %v, want %v", status.TokenExpirationTime, expiresAt)
+			}
+			if !refreshAt.After(now) {
+				t.Errorf("token is refreshed at %v, which has already passed", refreshAt)
+			}
+		})
+	}
+}
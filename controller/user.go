@@ -11,8 +11,8 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	v1alpha2 "perm8s/pkg/apis/perm8s/v1alpha1"
-	"reflect"
 	"slices"
+	"time"
 )
 
 func (c *Controller) enqueueUser(obj interface{}) {
@@ -38,8 +38,12 @@ func (c *Controller) processNextUserWorkItem(ctx context.Context) bool {
 	}
 
 	defer c.userWorkqueue.Done(objRef)
+	defer c.heartbeats.begin(kindUser)()
 
+	start := time.Now()
 	err := c.syncUserHandler(ctx, objRef.(cache.ObjectName))
+	observeReconcile(kindUser, start, err)
+
 	if err == nil {
 
 		c.userWorkqueue.Forget(objRef)
@@ -66,45 +70,66 @@ func (c *Controller) syncUserHandler(ctx context.Context, objectRef cache.Object
 		return err
 	}
 
-	serviceAccount, err := c.apiClient.ServiceAccounts(user.Namespace).Get(ctx, user.Name, v3.GetOptions{})
-
-	if err != nil {
-		if errors.IsNotFound(err) {
-			logger.Info("Service account does not exist yet, creating new", "accountName", user.Name, "namespace", user.Namespace)
-			serviceAccount, err = c.apiClient.ServiceAccounts(user.Namespace).Create(ctx, c.ServiceAccountFromUser(user), v3.CreateOptions{})
+	status := v1alpha2.UserStatus{
+		ObservedGeneration: user.Generation,
+		Conditions:         slices.Clone(user.Status.Conditions),
+	}
 
-			if err != nil {
-				logger.Error(err, "Error while creating serviceaccount", "user", user.Name)
-				return nil
-			}
+	var missingGroups []string
+	err = c.reconcileUser(ctx, user, &status, &missingGroups)
 
-			logger.Info("Service account created successfully")
+	setReadyCondition(&status.Conditions, user.Generation, err)
+	setDegradedCondition(&status.Conditions, user.Generation, ReasonGroupNotFound, missingGroups)
 
-			c.recorder.Event(user, v2.EventTypeNormal, SuccessCreated, MessageUserCreated)
+	if statusErr := c.updateUserStatus(ctx, user, status); statusErr != nil {
+		logger.Error(statusErr, "Error while updating User status", "user", user.Name)
+		if err == nil {
+			return statusErr
 		}
 	}
 
-	_, err = c.apiClient.Secrets(user.Namespace).Get(ctx, fmt.Sprintf("%v-usertoken", serviceAccount.Name), v3.GetOptions{})
+	return err
+}
+
+// reconcileUser makes sure the ServiceAccount, token and bindings of the given user exist and records them in the status
+func (c *Controller) reconcileUser(ctx context.Context, user *v1alpha2.User, status *v1alpha2.UserStatus, missingGroups *[]string) error {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "user", user.Name)
 
-	if err != nil {
-		if errors.IsNotFound(err) {
-			logger.Info("No token secret exists for user, creating secret", "user", user.Name, "serviceAccount", serviceAccount.Name)
+	now := time.Now()
+	memberships, membershipStates, nextMembershipChange := activeGroupMemberships(user, now)
+	status.Memberships = membershipStates
 
-			_, err = c.apiClient.Secrets(serviceAccount.Namespace).Create(ctx, c.AuthenticationSecretFromServiceAccount(serviceAccount, user), v3.CreateOptions{})
+	if !nextMembershipChange.IsZero() {
+		// bindings of time-bound memberships are created and removed once the user is reconciled at the boundary of their window
+		c.userWorkqueue.AddAfter(cache.ObjectName{Namespace: user.Namespace, Name: user.Name}, nextMembershipChange.Sub(now))
+	}
 
-			if err != nil {
-				logger.Error(err, "Error while creating authentication secret", "user", user.Name, "serviceAccount", serviceAccount.Name, "namespace", serviceAccount.Namespace)
-				return nil
-			}
+	if user.Spec.Suspended {
+		return c.suspendUser(ctx, user)
+	}
+
+	// included groups are bound like direct memberships, inclusion cycles are reported on the groups themselves
+	effectiveGroups, _ := c.expandGroups(user.Namespace, memberships)
+	status.EffectiveGroups = effectiveGroups
+
+	// users that only log in through their external identity do not need a ServiceAccount and its credentials
+	var secret *v2.Secret
+	var err error
+	if hasServiceAccount(user) {
+		if secret, err = c.reconcileServiceAccount(ctx, user, status); err != nil {
+			return err
 		}
+	} else if _, err = c.removeServiceAccount(ctx, user); err != nil {
+		return err
 	}
 
-	for _, group := range user.Spec.GroupMemberships {
+	for _, groupName := range effectiveGroups {
 		// we need to ensure that both the cluster group, the regular groups for each affected namespace, as well as the group object itself and everything else exists
-		group, err := c.groupLister.Groups(user.Namespace).Get(group)
+		group, err := c.groupLister.Groups(user.Namespace).Get(groupName)
 
 		if errors.IsNotFound(err) {
 			logger.Info("User has group which does not exist. No UserGroup sync will be done for this group.")
+			*missingGroups = append(*missingGroups, fmt.Sprintf("group %v does not exist", groupName))
 			continue
 		}
 
@@ -113,57 +138,31 @@ func (c *Controller) syncUserHandler(ctx context.Context, objectRef cache.Object
 			return err
 		}
 
-		// Cluster groups are groups that have their permissions assigned to the entire cluster. Permissions assigned to these roles will be available throughout every namespace
-		if group.Spec.ClusterGroup {
-			// check for clusterrolebinding
-			clusterRoleBinding, err := c.kubeclientset.RbacV1().ClusterRoleBindings().Get(ctx, fmt.Sprintf("%v-membership-%v", user.Name, group.Name), v3.GetOptions{})
-			if errors.IsNotFound(err) {
-				logger.Info("ClusterRoleBinding does not exist yet for user, creating")
-				clusterRoleBinding, err = c.kubeclientset.RbacV1().ClusterRoleBindings().Create(ctx, c.ClusterRoleBindingForUserMembership(user, group), v3.CreateOptions{})
-
-				if err != nil {
-					logger.Error(err, "Error while creating ClusterRoleBinding for UserGroup sync", "user", user.Name, "group", group.Name)
-					return err
-				}
-			}
-
-			desiredClusterRoleBinding := c.ClusterRoleBindingForUserMembership(user, group)
-			if !reflect.DeepEqual(clusterRoleBinding.Subjects, desiredClusterRoleBinding.Subjects) || !reflect.DeepEqual(clusterRoleBinding.RoleRef, desiredClusterRoleBinding.RoleRef) {
-				logger.Info("ClusterRoleBinding for UserGroup is out of sync, resyncing", "user", user.Name, "group", group.Name)
+		// the group finalizer removes all bindings of a group that is being deleted, so we must not recreate them
+		if group.DeletionTimestamp != nil {
+			logger.Info("User has group which is being deleted. No UserGroup sync will be done for this group.", "group", group.Name)
+			continue
+		}
 
-				_, err = c.kubeclientset.RbacV1().ClusterRoleBindings().Update(ctx, desiredClusterRoleBinding, v3.UpdateOptions{})
+		// Cluster groups are groups that have their permissions assigned to the entire cluster, namespaced groups only bind them in each of their namespaces
+		clusterRoleBindings, roleBindings, err := c.groupBindings(group, c.membershipBindingTemplate(user, group))
+		if err != nil {
+			return err
+		}
 
-				if err != nil {
-					logger.Error(err, "Error while updating ClusterRoleBinding for UserGroup sync", "user", user.Name, "group", group.Name)
-					return err
-				}
+		for _, desiredClusterRoleBinding := range clusterRoleBindings {
+			if err = c.ensureClusterRoleBinding(ctx, user, desiredClusterRoleBinding); err != nil {
+				logger.Error(err, "Error while syncing ClusterRoleBinding for UserGroup sync", "user", user.Name, "group", group.Name)
+				return err
 			}
-		} else {
-			for _, namespace := range group.Spec.Namespaces {
-				roleBinding, err := c.kubeclientset.RbacV1().RoleBindings(namespace).Get(ctx, fmt.Sprintf("%v-membership-%v", user.Name, group.Name), v3.GetOptions{})
-				if errors.IsNotFound(err) {
-					logger.Info("RoleBinding does not exist yet for user, creating", "user", user.Name, "namespace", namespace, "group", group.Name)
-					roleBinding, err = c.kubeclientset.RbacV1().RoleBindings(namespace).Create(ctx, c.RoleBindingForUserMembership(user, group, namespace), v3.CreateOptions{})
-
-					if err != nil {
-						logger.Error(err, "Error while creating RoleBinding for UserGroup sync", "user", user.Name, "group", group.Name, "namespace", namespace)
-						return err
-					}
-
-					c.recorder.Event(user, v2.EventTypeNormal, SuccessSynced, "Created RoleBinding for user in namespace "+namespace)
-				}
-
-				desiredRoleBinding := c.RoleBindingForUserMembership(user, group, namespace)
-				if !reflect.DeepEqual(roleBinding.Subjects, desiredRoleBinding.Subjects) || !reflect.DeepEqual(roleBinding.RoleRef, desiredRoleBinding.RoleRef) {
-					logger.Info("RoleBinding for UserGroup is out of sync, resyncing", "user", user.Name, "group", group.Name, "namespace", namespace)
-
-					_, err = c.kubeclientset.RbacV1().RoleBindings(namespace).Update(ctx, desiredRoleBinding, v3.UpdateOptions{})
-
-					if err != nil {
-						logger.Error(err, "Error while updating RoleBinding for UserGroup sync", "user", user.Name, "group", group.Name, "namespace", namespace)
-						return err
-					}
-				}
+
+			status.ClusterRoleBindings = append(status.ClusterRoleBindings, desiredClusterRoleBinding.Name)
+		}
+
+		for _, desiredRoleBinding := range roleBindings {
+			if err = c.ensureUserRoleBinding(ctx, user, desiredRoleBinding, status); err != nil {
+				logger.Error(err, "Error while syncing RoleBinding for UserGroup sync", "user", user.Name, "group", group.Name, "namespace", desiredRoleBinding.Namespace)
+				return err
 			}
 		}
 	}
@@ -171,54 +170,114 @@ func (c *Controller) syncUserHandler(ctx context.Context, objectRef cache.Object
 	// We need to make sure that there are no dangling RoleBindings in any namespaces.
 	// They are dangling if either:
 	// The user is no longer part of the given group OR
-	// The group no longer targets the specific namespace (and is a non-cluster group)
-	// If they are, we need to remove them
-	roleBindings, err := c.kubeclientset.RbacV1().RoleBindings("").List(ctx, v3.ListOptions{
-		LabelSelector: fmt.Sprintf("perm8s.tobiasgrether.com/user=%v,perm8s.tobiasgrether.com/namespace=%v", user.Name, user.Namespace),
-	})
+	// The group no longer targets the specific namespace or role
+	// Every RoleBinding that is still desired was recorded in the status above, so we remove all others
+	userSelector := v3.ListOptions{
+		LabelSelector: fmt.Sprintf("%v=%v,%v=%v", LabelUser, user.Name, LabelNamespace, user.Namespace),
+	}
+
+	roleBindings, err := c.kubeclientset.RbacV1().RoleBindings("").List(ctx, userSelector)
+	if err != nil {
+		return err
+	}
 
 	for _, roleBinding := range roleBindings.Items {
-		if groupName, ok := roleBinding.Labels["perm8s.tobiasgrether.com/group"]; ok {
-			if groupNamespace, ok := roleBinding.Labels["perm8s.tobiasgrether.com/namespace"]; ok {
-				group, err := c.groupLister.Groups(groupNamespace).Get(groupName)
-				if err != nil {
-					if errors.IsNotFound(err) {
-						logger.Info("RoleBinding exists for unknown role, deleting", "user", user.Name, "group", groupName, "namespace", roleBinding.Namespace)
-						err = c.kubeclientset.RbacV1().RoleBindings(roleBinding.Namespace).Delete(ctx, roleBinding.Name, v3.DeleteOptions{})
-
-						if err != nil {
-							logger.Error(err, "Failed to delete RoleBinding without associated Group", "user", user.Name, "group", groupName, "namespace", roleBinding.Namespace)
-						}
-					} else {
-						logger.Error(err, "Error while fetching group for RoleBinding validity check", "user", user.Name, "group", groupName, "namespace", roleBinding.Namespace, "roleBinding", roleBinding.Name)
-					}
-				}
-				if !group.Spec.ClusterGroup && !slices.Contains(group.Spec.Namespaces, roleBinding.Namespace) {
-					logger.Info("RoleBinding is associated with Namespace that is no longer associated with group, removing", "user", user.Name, "group", groupName, "namespace", roleBinding.Namespace, "roleBinding", roleBinding.Name)
-					err = c.kubeclientset.RbacV1().RoleBindings(roleBinding.Namespace).Delete(ctx, roleBinding.Name, v3.DeleteOptions{})
-
-					if err != nil {
-						logger.Error(err, "Failed to delete dangling RoleBinding (no associated group)", "user", user.Name, "group", groupName, "namespace", roleBinding.Namespace)
-					}
-
-					continue
-				}
-			}
-			if !slices.Contains(user.Spec.GroupMemberships, groupName) {
-				logger.Info("Removing dangling RoleBinding for user", "user", user.Name, "group", groupName, "namespace", roleBinding.Namespace)
-				err = c.kubeclientset.RbacV1().RoleBindings(roleBinding.Namespace).Delete(ctx, roleBinding.Name, v3.DeleteOptions{})
+		if slices.Contains(status.RoleBindings, fmt.Sprintf("%v/%v", roleBinding.Namespace, roleBinding.Name)) {
+			continue
+		}
+
+		logger.Info("Removing dangling RoleBinding for user", "user", user.Name, "group", roleBinding.Labels[LabelGroup], "namespace", roleBinding.Namespace, "roleBinding", roleBinding.Name)
+		err = c.kubeclientset.RbacV1().RoleBindings(roleBinding.Namespace).Delete(ctx, roleBinding.Name, v3.DeleteOptions{})
+
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete dangling RoleBinding", "user", user.Name, "namespace", roleBinding.Namespace, "roleBinding", roleBinding.Name)
+		}
+	}
+
+	// ClusterRoleBindings are dangling if the user is no longer an active member of their cluster group
+	clusterRoleBindings, err := c.kubeclientset.RbacV1().ClusterRoleBindings().List(ctx, userSelector)
+	if err != nil {
+		return err
+	}
+
+	for _, clusterRoleBinding := range clusterRoleBindings.Items {
+		if slices.Contains(status.ClusterRoleBindings, clusterRoleBinding.Name) {
+			continue
+		}
 
-				if err != nil {
-					logger.Error(err, "Failed to delete dangling RoleBinding (user no longer part of group)", "user", user.Name, "group", groupName, "namespace", roleBinding.Namespace)
-				}
+		logger.Info("Removing dangling ClusterRoleBinding for user", "user", user.Name, "group", clusterRoleBinding.Labels[LabelGroup], "clusterRoleBinding", clusterRoleBinding.Name)
+		err = c.kubeclientset.RbacV1().ClusterRoleBindings().Delete(ctx, clusterRoleBinding.Name, v3.DeleteOptions{})
+
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete dangling ClusterRoleBinding", "user", user.Name, "clusterRoleBinding", clusterRoleBinding.Name)
+		}
+	}
+
+	// the token controller fills in the token and CA bundle asynchronously, the update of the secret requeues the user
+	if secret != nil {
+		if err = c.reconcileKubeconfig(ctx, user, secret.Data["token"], secret.Data["ca.crt"], status); err != nil {
+			logger.Error(err, "Error while syncing kubeconfig secret", "user", user.Name)
+			return err
+		}
+	}
+
+	c.recorder.Event(user, v2.EventTypeNormal, SuccessSynced, MessageUserSynced)
+	return nil
+}
+
+// reconcileServiceAccount makes sure the ServiceAccount of the user and its token exist and returns the Secret holding the token
+func (c *Controller) reconcileServiceAccount(ctx context.Context, user *v1alpha2.User, status *v1alpha2.UserStatus) (*v2.Secret, error) {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "user", user.Name)
+
+	serviceAccount, err := c.apiClient.ServiceAccounts(user.Namespace).Get(ctx, user.Name, v3.GetOptions{})
+
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("Service account does not exist yet, creating new", "accountName", user.Name, "namespace", user.Namespace)
+			serviceAccount, err = c.apiClient.ServiceAccounts(user.Namespace).Create(ctx, c.ServiceAccountFromUser(user), v3.CreateOptions{})
+
+			if err != nil {
+				logger.Error(err, "Error while creating serviceaccount", "user", user.Name)
+				return nil, err
 			}
+
+			logger.Info("Service account created successfully")
+
+			c.recorder.Event(user, v2.EventTypeNormal, SuccessCreated, MessageUserCreated)
 		} else {
-			logger.Info("Warn: No groupname known for rolebinding", "namespace", roleBinding.Namespace, "roleBinding", roleBinding.Name)
+			return nil, err
+		}
+	} else if desiredLabels, changed := mergeMetadata(serviceAccount.Labels, userLabels(user)); changed {
+		logger.Info("ServiceAccount labels are out of sync, resyncing", "accountName", serviceAccount.Name)
+		serviceAccount = serviceAccount.DeepCopy()
+		serviceAccount.Labels = desiredLabels
+
+		if serviceAccount, err = c.apiClient.ServiceAccounts(user.Namespace).Update(ctx, serviceAccount, v3.UpdateOptions{FieldManager: FieldManager}); err != nil {
+			logger.Error(err, "Error while updating serviceaccount", "user", user.Name)
+			return nil, err
 		}
+	}
 
+	secret, err := c.reconcileUserToken(ctx, user, serviceAccount, status)
+	if err != nil {
+		logger.Error(err, "Error while syncing authentication secret", "user", user.Name, "serviceAccount", serviceAccount.Name)
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// ensureUserRoleBinding ensures a RoleBinding of the user and records it in the status
+func (c *Controller) ensureUserRoleBinding(ctx context.Context, user *v1alpha2.User, desired *v1.RoleBinding, status *v1alpha2.UserStatus) error {
+	if err := c.ensureRoleBinding(ctx, user, desired); err != nil {
+		return err
+	}
+
+	status.RoleBindings = append(status.RoleBindings, fmt.Sprintf("%v/%v", desired.Namespace, desired.Name))
+	if !slices.Contains(status.BoundNamespaces, desired.Namespace) {
+		status.BoundNamespaces = append(status.BoundNamespaces, desired.Namespace)
 	}
 
-	c.recorder.Event(user, v2.EventTypeNormal, SuccessSynced, MessageUserSynced)
 	return nil
 }
 
@@ -228,6 +287,7 @@ func (c *Controller) ServiceAccountFromUser(user *v1alpha2.User) *v2.ServiceAcco
 		ObjectMeta: v3.ObjectMeta{
 			Name:      user.Name,
 			Namespace: user.GetNamespace(),
+			Labels:    userLabels(user),
 			OwnerReferences: []v3.OwnerReference{
 				*v3.NewControllerRef(user, v1alpha2.SchemeGroupVersion.WithKind("User")),
 			},
@@ -237,56 +297,22 @@ func (c *Controller) ServiceAccountFromUser(user *v1alpha2.User) *v2.ServiceAcco
 }
 
 func (c *Controller) ClusterRoleBindingForUserMembership(user *v1alpha2.User, group *v1alpha2.Group) *v1.ClusterRoleBinding {
-	return &v1.ClusterRoleBinding{
-		ObjectMeta: v3.ObjectMeta{
-			Name: fmt.Sprintf("%v-membership-%v", user.Name, group.Name),
-			OwnerReferences: []v3.OwnerReference{
-				*v3.NewControllerRef(user, v1alpha2.SchemeGroupVersion.WithKind("User")),
-			},
-			Labels: map[string]string{
-				"perm8s.tobiasgrether.com/user":      user.Name,
-				"perm8s.tobiasgrether.com/namespace": user.Namespace,
-				"perm8s.tobiasgrether.com/group":     group.Name,
-			},
-		},
-		Subjects: []v1.Subject{
-			{
-				Name:      user.Name,
-				Kind:      "ServiceAccount",
-				Namespace: user.Namespace,
-			},
-		},
-		RoleRef: v1.RoleRef{
-			Kind:     "ClusterRole",
-			Name:     group.Name,
-			APIGroup: "rbac.authorization.k8s.io",
-		},
-	}
+	template := c.membershipBindingTemplate(user, group)
+	return template.clusterRoleBinding(template.name, clusterRoleRef(group.Name))
 }
 
 func (c *Controller) RoleBindingForUserMembership(user *v1alpha2.User, group *v1alpha2.Group, namespace string) *v1.RoleBinding {
-	return &v1.RoleBinding{
-		ObjectMeta: v3.ObjectMeta{
-			Name: fmt.Sprintf("%v-membership-%v", user.Name, group.Name),
-			Labels: map[string]string{
-				"perm8s.tobiasgrether.com/user":      user.Name,
-				"perm8s.tobiasgrether.com/namespace": user.Namespace,
-				"perm8s.tobiasgrether.com/group":     group.Name,
-			},
-			Namespace: namespace,
-		},
-		Subjects: []v1.Subject{
-			{
-				Name:      user.Name,
-				Kind:      "ServiceAccount",
-				Namespace: user.Namespace,
-			},
-		},
-		RoleRef: v1.RoleRef{
-			Kind:     "ClusterRole",
-			Name:     group.Name,
-			APIGroup: "rbac.authorization.k8s.io",
-		},
+	template := c.membershipBindingTemplate(user, group)
+	return template.roleBinding(namespace, template.name, clusterRoleRef(group.Name))
+}
+
+// membershipBindingTemplate grants the roles of the group to the subjects of the user
+func (c *Controller) membershipBindingTemplate(user *v1alpha2.User, group *v1alpha2.Group) bindingTemplate {
+	return bindingTemplate{
+		name:     MembershipBindingName(user.Name, group.Name),
+		labels:   membershipLabels(user, group),
+		subjects: c.userSubjects(user),
+		owner:    v3.NewControllerRef(user, v1alpha2.SchemeGroupVersion.WithKind("User")),
 	}
 }
 
@@ -296,6 +322,7 @@ func (c *Controller) AuthenticationSecretFromServiceAccount(serviceAccount *v2.S
 		ObjectMeta: v3.ObjectMeta{
 			Name:      fmt.Sprintf("%v-usertoken", serviceAccount.Name),
 			Namespace: serviceAccount.Namespace,
+			Labels:    userLabels(user),
 			OwnerReferences: []v3.OwnerReference{
 				*v3.NewControllerRef(user, v1alpha2.SchemeGroupVersion.WithKind("User")),
 			},
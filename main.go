@@ -1,26 +1,55 @@
 package main
 
 import (
+    "context"
     "flag"
     "net/http"
+    "os"
     _ "net/http/pprof"
 
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/util/uuid"
+    kubeinformers "k8s.io/client-go/informers"
     "k8s.io/client-go/kubernetes"
     "k8s.io/client-go/tools/clientcmd"
     v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
     "k8s.io/klog/v2" // Uncomment the following line to load the gcp plugin (only required to authenticate against GKE clusters).
     controller2 "perm8s/controller"
+    "perm8s/pkg/apis/perm8s/v1alpha1"
     clientset "perm8s/pkg/generated/clientset/versioned"
     informers "perm8s/pkg/generated/informers/externalversions"
     "perm8s/pkg/signals"
+    "perm8s/webhook"
+    "strings"
     "time"
 )
 
 var (
-    masterURL  string
-    kubeconfig string
-    profiling  bool
+    masterURL     string
+    kubeconfig    string
+    profiling     bool
+    clusterName   string
+    clusterServer string
+    tokenMode     string
+    tokenTTL      time.Duration
+    tokenAudience string
+    webhookAddress  string
+    webhookCertFile string
+    webhookKeyFile  string
+    trustAccessRequestApprovals bool
+    leaderElect             bool
+    leaderElectionLeaseName string
+    leaderElectionNamespace string
+    leaseDuration           time.Duration
+    renewDeadline           time.Duration
+    retryPeriod             time.Duration
+    metricsAddress          string
+    healthProbeAddress      string
+    workerStuckTimeout      time.Duration
+    oidcUsernamePrefix      string
+    oidcGroupsPrefix        string
 )
 
 func main() {
@@ -29,6 +58,11 @@ func main() {
 
     ctx := signals.SetupSignalHandler()
     logger := klog.FromContext(ctx)
+
+    if tokenMode != v1alpha1.TokenModeLegacy && tokenMode != v1alpha1.TokenModeTokenRequest {
+        logger.Error(nil, "Invalid token mode, expected legacy or tokenRequest", "tokenMode", tokenMode)
+        klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+    }
     
     if profiling {
         logger.Info("Starting profiling (pprof) server on :8444")
@@ -65,9 +99,89 @@ func main() {
     }
 
     informerFactory := informers.NewSharedInformerFactory(set, time.Second*30)
+    managedInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(client, time.Second*30, kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+        options.LabelSelector = controller2.ManagedLabelSelector
+    }))
+    // namespaces are watched unfiltered, as groups can select any namespace by its name or labels
+    kubeInformerFactory := kubeinformers.NewSharedInformerFactory(client, time.Second*30)
+
+    if clusterServer == "" {
+        clusterServer = cfg.Host
+    }
+
+    controller := controller2.NewController(ctx, client, set, apiClient, informerFactory.Perm8s().V1alpha1(), managedInformerFactory, kubeInformerFactory, controller2.Options{
+        ClusterName:        clusterName,
+        ClusterServer:      clusterServer,
+        TokenMode:          tokenMode,
+        TokenTTL:           tokenTTL,
+        TokenAudiences:     splitList(tokenAudience),
+        WorkerStuckTimeout: workerStuckTimeout,
+        TrustAccessRequestApprovals: trustAccessRequestApprovals,
+        OIDCUsernamePrefix: oidcUsernamePrefix,
+        OIDCGroupsPrefix:   oidcGroupsPrefix,
+        LeaderElection:     controller2.LeaderElectionOptions{
+            Enabled:        leaderElect,
+            LeaseName:      leaderElectionLeaseName,
+            LeaseNamespace: leaderElectionNamespace,
+            Identity:       leaderElectionIdentity(),
+            LeaseDuration:  leaseDuration,
+            RenewDeadline:  renewDeadline,
+            RetryPeriod:    retryPeriod,
+        },
+    })
+    if webhookCertFile != "" {
+        webhookServer := webhook.NewServer(client, informerFactory.Perm8s().V1alpha1().Groups().Lister(), informerFactory.Perm8s().V1alpha1().Users().Lister(), oidcUsernamePrefix)
+        logger.Info("Starting validating webhook server", "address", webhookAddress)
+        go func() {
+            if err := webhookServer.ListenAndServeTLS(ctx, webhookAddress, webhookCertFile, webhookKeyFile); err != nil {
+                logger.Error(err, "Error running validating webhook server")
+                klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+            }
+        }()
+    }
+
+    if leaderElect && leaderElectionNamespace == "" {
+        logger.Error(nil, "A namespace for the leader election Lease is required, set -leader-election-namespace or POD_NAMESPACE")
+        klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+    }
+
+    if metricsAddress != "" {
+        mux := http.NewServeMux()
+        mux.Handle("/metrics", promhttp.HandlerFor(controller2.Registry, promhttp.HandlerOpts{}))
+        metricsServer := &http.Server{Addr: metricsAddress, Handler: mux}
+
+        logger.Info("Starting metrics server", "address", metricsAddress)
+        go func() {
+            if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+                logger.Error(err, "Error running metrics server")
+                klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+            }
+        }()
+        go func() {
+            <-ctx.Done()
+            _ = metricsServer.Shutdown(context.Background())
+        }()
+    }
+
+    if healthProbeAddress != "" {
+        probeServer := &http.Server{Addr: healthProbeAddress, Handler: controller.HealthHandler()}
+
+        logger.Info("Starting health probe server", "address", healthProbeAddress)
+        go func() {
+            if err := probeServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+                logger.Error(err, "Error running health probe server")
+                klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+            }
+        }()
+        go func() {
+            <-ctx.Done()
+            _ = probeServer.Shutdown(context.Background())
+        }()
+    }
 
-    controller := controller2.NewController(ctx, client, set, apiClient, informerFactory.Perm8s().V1alpha1())
     informerFactory.Start(ctx.Done())
+    managedInformerFactory.Start(ctx.Done())
+    kubeInformerFactory.Start(ctx.Done())
 
     if err = controller.Run(ctx, 2); err != nil {
         logger.Error(err, "Error running user controller")
@@ -79,4 +193,43 @@ func init() {
     flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
     flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
     flag.BoolVar(&profiling, "profiling", false, "Enable to turn on pprof performance profiling for this program")
+    flag.StringVar(&clusterName, "cluster-name", "kubernetes", "The name of the cluster used in the kubeconfigs generated for users.")
+    flag.StringVar(&tokenMode, "token-mode", "legacy", "The default token mode for users, either legacy (non-expiring service account token secrets) or tokenRequest (short-lived tokens).")
+    flag.DurationVar(&tokenTTL, "token-ttl", time.Hour*24, "The default lifetime of tokens issued in tokenRequest mode.")
+    flag.StringVar(&tokenAudience, "token-audiences", "", "Comma separated default audiences of tokens issued in tokenRequest mode. Defaults to the API server audience.")
+    flag.StringVar(&webhookAddress, "webhook-address", ":9443", "The address the validating webhook server listens on.")
+    flag.StringVar(&webhookCertFile, "webhook-cert-file", "", "Path to the TLS certificate of the validating webhook server. The webhook server is only started when this is set.")
+    flag.StringVar(&webhookKeyFile, "webhook-key-file", "", "Path to the TLS private key of the validating webhook server.")
+    flag.BoolVar(&trustAccessRequestApprovals, "trust-access-request-approvals", false, "Grant approved AccessRequests. Only enable this once the validating webhook is installed with failurePolicy: Fail, as it authenticates the approver.")
+    flag.BoolVar(&leaderElect, "leader-elect", false, "Enable leader election, so that only one of several replicas runs the workers at a time.")
+    flag.StringVar(&leaderElectionLeaseName, "leader-election-lease-name", "perm8s-controller", "The name of the Lease used for leader election.")
+    flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", os.Getenv("POD_NAMESPACE"), "The namespace of the Lease used for leader election. Defaults to the POD_NAMESPACE environment variable.")
+    flag.DurationVar(&leaseDuration, "leader-election-lease-duration", 15*time.Second, "How long non-leading replicas wait before taking over a Lease that is no longer renewed.")
+    flag.DurationVar(&renewDeadline, "leader-election-renew-deadline", 10*time.Second, "How long the leader retries renewing the Lease before giving up leadership.")
+    flag.DurationVar(&retryPeriod, "leader-election-retry-period", 2*time.Second, "The interval between attempts to acquire or renew the Lease.")
+    flag.StringVar(&metricsAddress, "metrics-address", ":8080", "The address the Prometheus metrics endpoint listens on. Set to an empty string to disable it.")
+    flag.StringVar(&healthProbeAddress, "health-probe-address", ":8081", "The address the /healthz and /readyz probes listen on. Set to an empty string to disable them.")
+    flag.DurationVar(&workerStuckTimeout, "worker-stuck-timeout", 10*time.Minute, "How long a worker may spend on a single item before the liveness probe fails.")
+    flag.StringVar(&clusterServer, "cluster-server", "", "The API server URL written into the kubeconfigs generated for users. Defaults to the address the controller connects to.")
+    flag.StringVar(&oidcUsernamePrefix, "oidc-username-prefix", "", "The default prefix of the usernames of external user identities, matching the --oidc-username-prefix of the API server.")
+    flag.StringVar(&oidcGroupsPrefix, "oidc-groups-prefix", "", "The default prefix of the groups of external user identities, matching the --oidc-groups-prefix of the API server.")
+}
+
+// leaderElectionIdentity is unique per process, so that a restarted pod does not reuse the Lease of its predecessor
+func leaderElectionIdentity() string {
+    hostname, err := os.Hostname()
+    if err != nil {
+        hostname = "perm8s-controller"
+    }
+    return hostname + "_" + string(uuid.NewUUID())
+}
+
+func splitList(value string) []string {
+    var result []string
+    for _, entry := range strings.Split(value, ",") {
+        if entry = strings.TrimSpace(entry); entry != "" {
+            result = append(result, entry)
+        }
+    }
+    return result
 }
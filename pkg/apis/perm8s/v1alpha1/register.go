@@ -32,6 +32,7 @@ func addKnownTypes(scheme *runtime.Scheme) error {
         &Group{},
         &User{},
         &SynchronisationSource{},
+        &AccessRequest{},
     )
     metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
     return nil
@@ -3,42 +3,263 @@ package v1alpha1
 import (
 	v4 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 type AuthenticationSource string
 
+const (
+	// SyncNowAnnotation triggers an immediate synchronisation of a SynchronisationSource whenever its value changes,
+	// f.e. by setting it to the current timestamp
+	SyncNowAnnotation = "perm8s.tobiasgrether.com/sync-now"
+	// AllowMassDeletionAnnotation lets the next synchronisation of a SynchronisationSource exceed its deletion limit.
+	// Every new value is only honoured once, f.e. set it to the current timestamp
+	AllowMassDeletionAnnotation = "perm8s.tobiasgrether.com/allow-mass-deletion"
+)
+
+const (
+	// TokenModeLegacy issues non-expiring tokens through a kubernetes.io/service-account-token Secret
+	TokenModeLegacy = "legacy"
+	// TokenModeTokenRequest issues short-lived tokens through the TokenRequest API
+	TokenModeTokenRequest = "tokenRequest"
+)
+
+const (
+	// MembershipPending is the state of a membership whose NotBefore has not been reached yet
+	MembershipPending = "Pending"
+	// MembershipActive is the state of a membership that is currently granted
+	MembershipActive = "Active"
+	// MembershipExpired is the state of a membership whose ExpiresAt has passed
+	MembershipExpired = "Expired"
+)
+
+const (
+	// AccessRequestPending is the phase of an AccessRequest that has not been approved yet
+	AccessRequestPending = "Pending"
+	// AccessRequestActive is the phase of an AccessRequest whose membership is currently granted
+	AccessRequestActive = "Active"
+	// AccessRequestExpired is the phase of an AccessRequest whose membership has been revoked after its window
+	AccessRequestExpired = "Expired"
+	// AccessRequestDenied is the phase of an AccessRequest that cannot be granted, f.e. because the group cannot be requested
+	AccessRequestDenied = "Denied"
+)
+
+const (
+	// SyncTargetUsers creates a User for every account of a SynchronisationSource
+	SyncTargetUsers = "users"
+	// SyncTargetGroupSubjects binds the mapped Groups of a SynchronisationSource to OIDC group claims instead of creating Users
+	SyncTargetGroupSubjects = "groupSubjects"
+)
+
+const (
+	// SyncModeApply creates, updates and deletes the Users of a SynchronisationSource
+	SyncModeApply = "apply"
+	// SyncModePlan only computes the changes to the Users of a SynchronisationSource without applying them
+	SyncModePlan = "plan"
+)
+
+const (
+	// DeprovisioningDelete deletes Users that are no longer returned by their SynchronisationSource
+	DeprovisioningDelete = "delete"
+	// DeprovisioningSuspend suspends Users that are no longer returned by their SynchronisationSource
+	DeprovisioningSuspend = "suspend"
+	// DeprovisioningSuspendThenDelete suspends Users that are no longer returned by their SynchronisationSource
+	// and deletes them once they have been suspended for longer than DeleteAfter
+	DeprovisioningSuspendThenDelete = "suspendThenDelete"
+)
+
+const (
+	// ConditionReady is true once every object managed for the resource has been reconciled
+	ConditionReady = "Ready"
+	// ConditionDegraded is true when the resource was reconciled, but parts of it could not be applied (f.e. unknown groups)
+	ConditionDegraded = "Degraded"
+	// ConditionSourceReachable is true when the last request against the upstream synchronisation source succeeded
+	ConditionSourceReachable = "SourceReachable"
+	// ConditionDeletionLimitExceeded is true when the last synchronisation would have deleted more Users than allowed
+	ConditionDeletionLimitExceeded = "DeletionLimitExceeded"
+	// ConditionApproved is true once an AccessRequest has been approved by a member of the approver group
+	ConditionApproved = "Approved"
+)
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
-// +genclient:noStatus
 // +kubeBuilder:scope=Cluster
 // +kubeBuilder:resource:scope=Cluster
 // +kubeBuilder:resource.scope=Cluster
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:JSONPath=".spec.description",name=Description,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.memberCount",name=Members,type=integer
+// +kubebuilder:printcolumn:JSONPath=".status.clusterRoleName",name=Cluster Role,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='Ready')].status",name=Ready,type=string
 // +kubebuilder:field:scope=Cluster
 type Group struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
 	Spec GroupSpec `json:"spec"`
+	// +kubebuilder:validation:Optional
+	Status GroupStatus `json:"status,omitempty"`
 }
 
 type GroupSpec struct {
-	DisplayName  string          `json:"displayName"`
-	Description  string          `json:"description"`
-	Permissions  []v4.PolicyRule `json:"permissions"ƒ`
-	Namespaces   []string        `json:"namespaces"`
-	ClusterGroup bool            `json:"clusterGroup"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description"`
+	// Permissions are rendered into a ClusterRole that is generated for this group.
+	// Groups that only reference existing roles do not need any
+	// +kubebuilder:validation:Optional
+	Permissions []v4.PolicyRule `json:"permissions"`
+	// ClusterPermissions are rendered into a ClusterRole named <group>:cluster that is bound cluster wide, even for
+	// namespaced groups. This allows a single group to grant f.e. read access to nodes next to its namespaced permissions
+	// +kubebuilder:validation:Optional
+	ClusterPermissions []v4.PolicyRule `json:"clusterPermissions,omitempty"`
+	// Namespaces are the namespaces a namespaced group grants its permissions in. Entries may be glob patterns like
+	// team-*, which match every existing namespace with a matching name
+	Namespaces []string `json:"namespaces"`
+	// NamespaceSelector additionally selects the namespaces of a namespaced group by their labels
+	// +kubebuilder:validation:Optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	ClusterGroup      bool                  `json:"clusterGroup"`
+	// NamespacedRoles renders the permissions of a namespaced group into a Role in each of its namespaces instead of a
+	// single ClusterRole, and does the same for the permissions of every NamespacePermissions entry. Roles of namespaces
+	// the group no longer applies to are removed. Aggregated groups always use a ClusterRole
+	// +kubebuilder:validation:Optional
+	NamespacedRoles bool `json:"namespacedRoles,omitempty"`
+	// ClusterRoleRefs are the names of existing ClusterRoles, f.e. view or admin, that are bound to the members in
+	// addition to the generated ClusterRole. Like the generated one, they are bound cluster wide for cluster groups
+	// and in every namespace of the group otherwise
+	// +kubebuilder:validation:Optional
+	ClusterRoleRefs []string `json:"clusterRoleRefs,omitempty"`
+	// RoleRefs are existing Roles that are bound to the members in their namespace
+	// +kubebuilder:validation:Optional
+	RoleRefs []NamespacedRoleRef `json:"roleRefs,omitempty"`
+	// NamespacePermissions grant additional permissions in a subset of namespaces, on top of the permissions the group
+	// grants everywhere it applies. Each entry gets its own ClusterRole, which is bound in the namespaces of the entry only
+	// +kubebuilder:validation:Optional
+	NamespacePermissions []NamespacePermissions `json:"namespacePermissions,omitempty"`
+	// Subjects are bound to the roles of this group directly, without any User objects
+	// +kubebuilder:validation:Optional
+	Subjects *GroupSubjects `json:"subjects,omitempty"`
+	// AggregationLabels turns the generated ClusterRole into an aggregated one. Every entry selects ClusterRoles by
+	// their labels, whose rules are combined into the ClusterRole of this group by the Kubernetes aggregation controller
+	// +kubebuilder:validation:Optional
+	AggregationLabels []map[string]string `json:"aggregationLabels,omitempty"`
+	// AggregateTo labels the generated ClusterRole so that its rules are aggregated into the built-in view, edit or admin ClusterRoles
+	// +kubebuilder:validation:Optional
+	AggregateTo []AggregationTarget `json:"aggregateTo,omitempty"`
+	// AccessRequests allows Users to request a temporary membership in this group through an AccessRequest.
+	// Groups without it cannot be requested
+	// +kubebuilder:validation:Optional
+	AccessRequests *GroupAccessRequestPolicy `json:"accessRequests,omitempty"`
+	// Includes lists groups whose permissions are granted to the members of this group as well. Inclusion is transitive,
+	// every included group keeps its own scope and cycles are ignored
+	// +kubebuilder:validation:Optional
+	Includes []string `json:"includes,omitempty"`
+}
+
+type GroupSubjects struct {
+	// OIDCGroups are values of the groups claim of the OIDC integration of the API server. Everybody in one of these
+	// groups receives the permissions of this group and of the groups it includes
+	// +kubebuilder:validation:Optional
+	OIDCGroups []string `json:"oidcGroups,omitempty"`
+	// GroupsPrefix is prepended to every OIDC group and has to match the --oidc-groups-prefix of the API server.
+	// The default of the controller is used when it is not set
+	// +kubebuilder:validation:Optional
+	GroupsPrefix *string `json:"groupsPrefix,omitempty"`
+}
+
+type NamespacePermissions struct {
+	// Name identifies the entry within the group and is part of the names of its ClusterRole and bindings
+	Name string `json:"name"`
+	// Namespaces the entry applies to. Like the namespaces of the group, entries may be glob patterns
+	// +kubebuilder:validation:Optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// NamespaceSelector additionally selects the namespaces of the entry by their labels
+	// +kubebuilder:validation:Optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Permissions are rendered into a ClusterRole named <group>:ns:<name>, or into a Role of that name in each
+	// namespace of the entry if the group uses namespaced Roles
+	// +kubebuilder:validation:Optional
+	Permissions []v4.PolicyRule `json:"permissions,omitempty"`
+	// ClusterRoleRef is the name of an existing ClusterRole that is bound in the namespaces of the entry instead
+	// +kubebuilder:validation:Optional
+	ClusterRoleRef string `json:"clusterRoleRef,omitempty"`
+}
+
+// AggregationTarget is one of the built-in user-facing ClusterRoles
+// +kubebuilder:validation:Enum=view;edit;admin
+type AggregationTarget string
+
+type NamespacedRoleRef struct {
+	// Namespace is the namespace of the Role, which is also the namespace the members are bound in
+	Namespace string `json:"namespace"`
+	// Name is the name of the Role
+	Name string `json:"name"`
+}
+
+type GroupAccessRequestPolicy struct {
+	// ApproverGroup is the group whose members may approve AccessRequests for this group
+	ApproverGroup string `json:"approverGroup"`
+	// MaxDuration is the longest window an AccessRequest for this group may ask for
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:="8h"
+	MaxDuration *metav1.Duration `json:"maxDuration,omitempty"`
+}
+
+type GroupStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// MemberCount is the number of Users that list this group in their GroupMemberships
+	MemberCount int `json:"memberCount"`
+	// ClusterRoleName is the name of the ClusterRole rendered from the permissions of this group.
+	// It is empty if the group only references existing roles or uses namespaced Roles
+	ClusterRoleName string `json:"clusterRoleName,omitempty"`
+	// ClusterPermissionsRoleName is the name of the ClusterRole rendered from the cluster permissions of this group
+	ClusterPermissionsRoleName string `json:"clusterPermissionsRoleName,omitempty"`
+	// Roles lists the Roles rendered for this group as namespace/name, if it uses namespaced Roles
+	Roles []string `json:"roles,omitempty"`
+	// ConflictingRoles lists the Roles as namespace/name that the group would render, but that already exist without
+	// belonging to it. They are left untouched and the members of the group are not bound to them
+	ConflictingRoles []string `json:"conflictingRoles,omitempty"`
+	// ConflictingClusterRoles lists the ClusterRoles the group would render, but that already exist without belonging
+	// to it, like the built-in ClusterRoles or the ClusterRole of a group of the same name in another namespace. They
+	// are left untouched and the members of the group are not bound to them
+	ConflictingClusterRoles []string `json:"conflictingClusterRoles,omitempty"`
+	// SubjectBindings lists the bindings that grant this group to its OIDC group subjects, as namespace/name for RoleBindings
+	SubjectBindings []string `json:"subjectBindings,omitempty"`
+	// IncludedGroups lists every group that is included by this group, directly or transitively
+	IncludedGroups []string `json:"includedGroups,omitempty"`
+	// Namespaces lists the namespaces the members of a namespaced group are currently bound in
+	Namespaces []string `json:"namespaces,omitempty"`
+	// NamespacePermissions lists the namespaces every entry of spec.namespacePermissions is currently bound in
+	NamespacePermissions []NamespacePermissionsStatus `json:"namespacePermissions,omitempty"`
+}
+
+type NamespacePermissionsStatus struct {
+	Name string `json:"name"`
+	// ClusterRoleName is the name of the ClusterRole rendered from the permissions of the entry, or the referenced one.
+	// It is empty if the entry is rendered into namespaced Roles
+	ClusterRoleName string `json:"clusterRoleName,omitempty"`
+	// Namespaces are the namespaces the entry is currently bound in
+	Namespaces []string `json:"namespaces,omitempty"`
 }
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
-// +genclient:noStatus
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:JSONPath=".spec.type",name=Source Type,type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.mode",name=Mode,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.userCount",name=Users,type=integer
+// +kubebuilder:printcolumn:JSONPath=".status.lastSyncTime",name=Last Sync,type=date
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='Ready')].status",name=Ready,type=string
 type SynchronisationSource struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
 	Spec SynchronisationSourceSpec `json:"spec"`
+	// +kubebuilder:validation:Optional
+	Status SynchronisationSourceStatus `json:"status,omitempty"`
 }
 
 type SynchronisationSourceSpec struct {
@@ -46,6 +267,8 @@ type SynchronisationSourceSpec struct {
 	Type string `json:"type"`
 	// +kubebuilder:validation:Optional
 	Authentik *AuthentikSynchronisationSourceSpec `json:"authentik"`
+	// +kubebuilder:validation:Optional
+	LDAP *LDAPSynchronisationSourceSpec `json:"ldap"`
 	// GroupMappings should be a map internal group identifier => Kubernetes Group Name
 	// This is useful when your IdP or SyncSource returns some kind of UUID for the groups,
 	// but you want human-readable named groups in the cluster
@@ -54,6 +277,55 @@ type SynchronisationSourceSpec struct {
 	// +kubebuilder:Optional
 	// +kubebuilder:validation:default:=[]
 	DefaultGroups *[]string `json:"defaultGroups"`
+	// SyncInterval is the minimum time between two synchronisations against the upstream source
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:="5m"
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
+	// Schedule is an optional cron expression (f.e. "0 */6 * * *") that replaces SyncInterval when set
+	// +kubebuilder:validation:Optional
+	Schedule string `json:"schedule,omitempty"`
+	// Mode is either apply, which creates, updates and deletes Users according to the source,
+	// or plan, which only computes these changes and publishes them in the status and as Events
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=apply;plan
+	// +kubebuilder:default:=apply
+	Mode string `json:"mode,omitempty"`
+	// DeletionLimit aborts a synchronisation that would delete more Users than allowed, f.e. because the source
+	// returned an empty list after a permission change. There is no limit when it is left empty
+	// +kubebuilder:validation:Optional
+	DeletionLimit *SynchronisationDeletionLimit `json:"deletionLimit,omitempty"`
+	// Deprovisioning decides what happens to Users that are no longer returned by the source. They are deleted when it is left empty
+	// +kubebuilder:validation:Optional
+	Deprovisioning *DeprovisioningPolicy `json:"deprovisioning,omitempty"`
+	// Target is either users, which creates a User for every account of the source, or groupSubjects, which binds the
+	// Groups of the GroupMappings directly to the keys of the mappings as OIDC group claims without fetching any accounts.
+	// In groupSubjects mode, the keys have to be the values the IdP puts into the groups claim
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=users;groupSubjects
+	// +kubebuilder:default:=users
+	Target string `json:"target,omitempty"`
+}
+
+type DeprovisioningPolicy struct {
+	// Policy is either delete, suspend or suspendThenDelete
+	// +kubebuilder:validation:Enum=delete;suspend;suspendThenDelete
+	// +kubebuilder:default:=delete
+	Policy string `json:"policy"`
+	// DeleteAfter is how long a User stays suspended before it is deleted with the suspendThenDelete policy, f.e. 720h
+	// +kubebuilder:validation:Optional
+	DeleteAfter *metav1.Duration `json:"deleteAfter,omitempty"`
+}
+
+type SynchronisationDeletionLimit struct {
+	// MaxDeletions is the maximum number of Users a single synchronisation may delete
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	MaxDeletions *int32 `json:"maxDeletions,omitempty"`
+	// MaxDeletionPercentage is the maximum share of the Users of the source, in percent, a single synchronisation may delete
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MaxDeletionPercentage *int32 `json:"maxDeletionPercentage,omitempty"`
 }
 
 type AuthentikSynchronisationSourceSpec struct {
@@ -65,6 +337,95 @@ type AuthentikSynchronisationSourceSpec struct {
 	RequiredGroups []string `json:"requiredGroups"`
 }
 
+type LDAPSynchronisationSourceSpec struct {
+	// URL of the directory server, either ldap://host:port or ldaps://host:port
+	URL string `json:"url"`
+	// BindDN is the distinguished name used to authenticate against the directory.
+	// Leaving it empty will use an anonymous bind
+	// +kubebuilder:validation:Optional
+	BindDN string `json:"bindDN"`
+	// SecretName references a Secret in the namespace of the SynchronisationSource which holds the bind password in the key "password"
+	// +kubebuilder:validation:Optional
+	SecretName string `json:"secretName"`
+	// UserBaseDN is the subtree that is searched for users
+	UserBaseDN string `json:"userBaseDN"`
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:="(objectClass=person)"
+	UserFilter string `json:"userFilter"`
+	// UserNameAttribute is the attribute that is used as the name of the resulting User, f.e. uid or sAMAccountName
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=uid
+	UserNameAttribute string `json:"userNameAttribute"`
+	// GroupBaseDN is the subtree that is searched for groups
+	GroupBaseDN string `json:"groupBaseDN"`
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:="(|(objectClass=groupOfNames)(objectClass=groupOfUniqueNames)(objectClass=posixGroup)(objectClass=group))"
+	GroupFilter string `json:"groupFilter"`
+	// GroupNameAttribute is the attribute of a group that is used as the group identifier in GroupMappings and RequiredGroups
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=cn
+	GroupNameAttribute string `json:"groupNameAttribute"`
+	// MemberAttribute is the attribute of a group listing its members. Values may either be the DN of a user
+	// (member, uniqueMember) or the value of the UserNameAttribute (memberUid)
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=member
+	MemberAttribute string `json:"memberAttribute"`
+	// StartTLS upgrades a plain ldap:// connection to TLS before binding
+	// +kubebuilder:validation:Optional
+	StartTLS bool `json:"startTLS"`
+	// CASecretName references a Secret in the namespace of the SynchronisationSource which holds the PEM encoded CA bundle
+	// used to verify the directory server in the key "ca.crt". The system roots are used when it is left empty
+	// +kubebuilder:validation:Optional
+	CASecretName string `json:"caSecretName"`
+	// +kubebuilder:validation:Optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+	// RequiredGroups is a list where a user only gets considered for this data source once they are a member of at least one of these groups
+	// Leaving this array empty will autopass all users
+	// +kubebuilder:validation:Optional
+	RequiredGroups []string `json:"requiredGroups"`
+}
+
+type SynchronisationSourceStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// LastSyncTime is the time of the last synchronisation that completed without errors
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// UserCount is the number of users returned by the source during the last successful synchronisation
+	UserCount int `json:"userCount"`
+	// LastError holds the error of the last failed synchronisation and is cleared once a synchronisation succeeds
+	LastError string `json:"lastError,omitempty"`
+	// LastAttemptTime is the time of the last synchronisation, regardless of its outcome
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+	// LastHandledSyncRequest is the value of the sync-now annotation that was last acted upon
+	LastHandledSyncRequest string `json:"lastHandledSyncRequest,omitempty"`
+	// LastHandledDeletionOverride is the value of the allow-mass-deletion annotation that was last acted upon
+	LastHandledDeletionOverride string `json:"lastHandledDeletionOverride,omitempty"`
+	// Plan holds the changes computed during the last synchronisation in plan mode. It is cleared in apply mode
+	Plan *SynchronisationPlan `json:"plan,omitempty"`
+}
+
+// SynchronisationPlan lists the changes to the Users of a SynchronisationSource that applying it would make
+type SynchronisationPlan struct {
+	// Create are the names of the Users that would be created
+	Create []string `json:"create,omitempty"`
+	// Update are the Users whose group memberships would change
+	Update []PlannedUserUpdate `json:"update,omitempty"`
+	// Suspend are the names of the Users that would be suspended
+	Suspend []string `json:"suspend,omitempty"`
+	// Delete are the names of the Users that would be deleted
+	Delete []string `json:"delete,omitempty"`
+}
+
+type PlannedUserUpdate struct {
+	Name string `json:"name"`
+	// AddedGroups are the groups the User would become a member of
+	AddedGroups []string `json:"addedGroups,omitempty"`
+	// RemovedGroups are the groups the User would no longer be a member of
+	RemovedGroups []string `json:"removedGroups,omitempty"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type SynchronisationSourceList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -75,20 +436,123 @@ type SynchronisationSourceList struct {
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
-// +genclient:noStatus
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:JSONPath=".spec.authenticationSource",name=Authentication Source,type=string
 // +kubebuilder:printcolumn:JSONPath=".spec.displayName",name=Display Name,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.boundNamespaces",name=Namespaces,type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.suspended",name=Suspended,type=boolean
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='Ready')].status",name=Ready,type=string
 type User struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
 	Spec UserSpec `json:"spec"`
+	// +kubebuilder:validation:Optional
+	Status UserStatus `json:"status,omitempty"`
 }
 
 type UserSpec struct {
 	DisplayName          string   `json:"displayName"`
 	AuthenticationSource string   `json:"authenticationSource"`
 	GroupMemberships     []string `json:"groupMemberships"`
+	// Memberships are group memberships that can be limited to a time window. They are granted in addition to GroupMemberships
+	// +kubebuilder:validation:Optional
+	Memberships []GroupMembership `json:"memberships,omitempty"`
+	// Token configures how the credentials of the user are issued. The defaults of the controller are used when it is left empty
+	// +kubebuilder:validation:Optional
+	Token *UserTokenSpec `json:"token,omitempty"`
+	// Suspended removes all bindings of the user and revokes its tokens, while keeping the User itself
+	// +kubebuilder:validation:Optional
+	Suspended bool `json:"suspended,omitempty"`
+	// Identity is the identity the user logs in with through an external authenticator, like the OIDC integration of the
+	// API server. The bindings of the user are granted to it in addition to the ServiceAccount of the user
+	// +kubebuilder:validation:Optional
+	Identity *ExternalIdentity `json:"identity,omitempty"`
+	// ServiceAccount controls whether a ServiceAccount, token and kubeconfig are created for the user.
+	// Users with an identity can disable it to only log in through their identity
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=true
+	ServiceAccount *bool `json:"serviceAccount,omitempty"`
+}
+
+type ExternalIdentity struct {
+	// Username is the value of the username claim of the user, which is bound as a User subject
+	// +kubebuilder:validation:Optional
+	Username string `json:"username,omitempty"`
+	// Groups are values of the groups claim, each of them is bound as a Group subject
+	// +kubebuilder:validation:Optional
+	Groups []string `json:"groups,omitempty"`
+	// UsernamePrefix is prepended to the username and has to match the --oidc-username-prefix of the API server.
+	// The default of the controller is used when it is not set
+	// +kubebuilder:validation:Optional
+	UsernamePrefix *string `json:"usernamePrefix,omitempty"`
+	// GroupsPrefix is prepended to every group and has to match the --oidc-groups-prefix of the API server.
+	// The default of the controller is used when it is not set
+	// +kubebuilder:validation:Optional
+	GroupsPrefix *string `json:"groupsPrefix,omitempty"`
+}
+
+// GroupMembership is a membership in a group that is only granted within an optional time window
+type GroupMembership struct {
+	Group string `json:"group"`
+	// NotBefore is the time from which on the membership is granted. It is granted right away when left empty
+	// +kubebuilder:validation:Optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+	// ExpiresAt is the time at which the membership is revoked. It never expires when left empty
+	// +kubebuilder:validation:Optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// AccessRequest is the AccessRequest that granted this membership. It is set by the controller, which revokes the
+	// membership once the request expires or is deleted
+	// +kubebuilder:validation:Optional
+	AccessRequest *AccessRequestReference `json:"accessRequest,omitempty"`
+}
+
+type AccessRequestReference struct {
+	Name string    `json:"name"`
+	UID  types.UID `json:"uid"`
+}
+
+type UserTokenSpec struct {
+	// Mode is either legacy, which creates a non-expiring kubernetes.io/service-account-token Secret,
+	// or tokenRequest, which mints short-lived tokens through the TokenRequest API and refreshes them before they expire
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=legacy;tokenRequest
+	Mode string `json:"mode,omitempty"`
+	// TTL is the requested lifetime of tokens issued in tokenRequest mode
+	// +kubebuilder:validation:Optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+	// Audiences are the intended audiences of tokens issued in tokenRequest mode. The API server audience is used when left empty
+	// +kubebuilder:validation:Optional
+	Audiences []string `json:"audiences,omitempty"`
+}
+
+type UserStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// BoundNamespaces lists every namespace the user has received a RoleBinding in
+	BoundNamespaces []string `json:"boundNamespaces,omitempty"`
+	// RoleBindings lists the RoleBindings managed for this user as namespace/name
+	RoleBindings []string `json:"roleBindings,omitempty"`
+	// ClusterRoleBindings lists the ClusterRoleBindings managed for this user
+	ClusterRoleBindings []string `json:"clusterRoleBindings,omitempty"`
+	// KubeconfigSecretName is the name of the Secret holding the generated kubeconfig of this user in the key "kubeconfig"
+	KubeconfigSecretName string `json:"kubeconfigSecretName,omitempty"`
+	// TokenExpirationTime is the time the currently issued token expires at. It is empty for legacy tokens, which never expire
+	TokenExpirationTime *metav1.Time `json:"tokenExpirationTime,omitempty"`
+	// Memberships reports the state of every time-bound membership of the user
+	Memberships []GroupMembershipStatus `json:"memberships,omitempty"`
+	// EffectiveGroups lists the groups the user is currently bound to, including the ones included by its groups
+	EffectiveGroups []string `json:"effectiveGroups,omitempty"`
+}
+
+type GroupMembershipStatus struct {
+	Group string `json:"group"`
+	// State is Pending before NotBefore, Active within the time window and Expired after ExpiresAt
+	State     string       `json:"state"`
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -108,3 +572,59 @@ type UserList struct {
 
 	Items []User `json:"items"`
 }
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".spec.user",name=User,type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.group",name=Group,type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.duration",name=Duration,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.phase",name=Phase,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.expiresAt",name=Expires,type=date
+// AccessRequest asks for a temporary membership of a User in a Group. Once a member of the approver group of the
+// Group approves it, the membership is granted for the requested duration and revoked afterwards
+type AccessRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AccessRequestSpec `json:"spec"`
+	// +kubebuilder:validation:Optional
+	Status AccessRequestStatus `json:"status,omitempty"`
+}
+
+type AccessRequestSpec struct {
+	// User is the name of the User in the namespace of the request that should receive the membership
+	User string `json:"user"`
+	// Group is the name of the Group in the namespace of the request that is requested
+	Group string `json:"group"`
+	// Duration is how long the membership is granted for once it has been approved
+	Duration metav1.Duration `json:"duration"`
+	// Reason explains to the approvers why access is needed
+	Reason string `json:"reason"`
+	// ApprovedBy is set by an approver to the name of their own User to approve the request
+	// +kubebuilder:validation:Optional
+	ApprovedBy string `json:"approvedBy,omitempty"`
+}
+
+type AccessRequestStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Phase is Pending, Active, Expired or Denied
+	Phase string `json:"phase,omitempty"`
+	// ApprovedBy is the approver the membership was granted on behalf of
+	ApprovedBy string `json:"approvedBy,omitempty"`
+	// GrantedAt is the time the membership was granted
+	GrantedAt *metav1.Time `json:"grantedAt,omitempty"`
+	// ExpiresAt is the time the membership is revoked
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type AccessRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []AccessRequest `json:"items"`
+}
@@ -22,10 +22,136 @@ limitations under the License.
 package v1alpha1
 
 import (
-	v1 "k8s.io/api/rbac/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequest) DeepCopyInto(out *AccessRequest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequest.
+func (in *AccessRequest) DeepCopy() *AccessRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccessRequest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestList) DeepCopyInto(out *AccessRequestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AccessRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestList.
+func (in *AccessRequestList) DeepCopy() *AccessRequestList {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccessRequestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestReference) DeepCopyInto(out *AccessRequestReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestReference.
+func (in *AccessRequestReference) DeepCopy() *AccessRequestReference {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestSpec) DeepCopyInto(out *AccessRequestSpec) {
+	*out = *in
+	out.Duration = in.Duration
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestSpec.
+func (in *AccessRequestSpec) DeepCopy() *AccessRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestStatus) DeepCopyInto(out *AccessRequestStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GrantedAt != nil {
+		in, out := &in.GrantedAt, &out.GrantedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestStatus.
+func (in *AccessRequestStatus) DeepCopy() *AccessRequestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthentikSynchronisationSourceSpec) DeepCopyInto(out *AuthentikSynchronisationSourceSpec) {
 	*out = *in
@@ -47,12 +173,65 @@ func (in *AuthentikSynchronisationSourceSpec) DeepCopy() *AuthentikSynchronisati
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeprovisioningPolicy) DeepCopyInto(out *DeprovisioningPolicy) {
+	*out = *in
+	if in.DeleteAfter != nil {
+		in, out := &in.DeleteAfter, &out.DeleteAfter
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeprovisioningPolicy.
+func (in *DeprovisioningPolicy) DeepCopy() *DeprovisioningPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DeprovisioningPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalIdentity) DeepCopyInto(out *ExternalIdentity) {
+	*out = *in
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UsernamePrefix != nil {
+		in, out := &in.UsernamePrefix, &out.UsernamePrefix
+		*out = new(string)
+		**out = **in
+	}
+	if in.GroupsPrefix != nil {
+		in, out := &in.GroupsPrefix, &out.GroupsPrefix
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalIdentity.
+func (in *ExternalIdentity) DeepCopy() *ExternalIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Group) DeepCopyInto(out *Group) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -74,6 +253,27 @@ func (in *Group) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupAccessRequestPolicy) DeepCopyInto(out *GroupAccessRequestPolicy) {
+	*out = *in
+	if in.MaxDuration != nil {
+		in, out := &in.MaxDuration, &out.MaxDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupAccessRequestPolicy.
+func (in *GroupAccessRequestPolicy) DeepCopy() *GroupAccessRequestPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupAccessRequestPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GroupList) DeepCopyInto(out *GroupList) {
 	*out = *in
@@ -107,12 +307,72 @@ func (in *GroupList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupMembership) DeepCopyInto(out *GroupMembership) {
+	*out = *in
+	if in.NotBefore != nil {
+		in, out := &in.NotBefore, &out.NotBefore
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.AccessRequest != nil {
+		in, out := &in.AccessRequest, &out.AccessRequest
+		*out = new(AccessRequestReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupMembership.
+func (in *GroupMembership) DeepCopy() *GroupMembership {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupMembership)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupMembershipStatus) DeepCopyInto(out *GroupMembershipStatus) {
+	*out = *in
+	if in.NotBefore != nil {
+		in, out := &in.NotBefore, &out.NotBefore
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupMembershipStatus.
+func (in *GroupMembershipStatus) DeepCopy() *GroupMembershipStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupMembershipStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GroupSpec) DeepCopyInto(out *GroupSpec) {
 	*out = *in
 	if in.Permissions != nil {
 		in, out := &in.Permissions, &out.Permissions
-		*out = make([]v1.PolicyRule, len(*in))
+		*out = make([]rbacv1.PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClusterPermissions != nil {
+		in, out := &in.ClusterPermissions, &out.ClusterPermissions
+		*out = make([]rbacv1.PolicyRule, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -122,6 +382,61 @@ func (in *GroupSpec) DeepCopyInto(out *GroupSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterRoleRefs != nil {
+		in, out := &in.ClusterRoleRefs, &out.ClusterRoleRefs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RoleRefs != nil {
+		in, out := &in.RoleRefs, &out.RoleRefs
+		*out = make([]NamespacedRoleRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespacePermissions != nil {
+		in, out := &in.NamespacePermissions, &out.NamespacePermissions
+		*out = make([]NamespacePermissions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Subjects != nil {
+		in, out := &in.Subjects, &out.Subjects
+		*out = new(GroupSubjects)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AggregationLabels != nil {
+		in, out := &in.AggregationLabels, &out.AggregationLabels
+		*out = make([]map[string]string, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = make(map[string]string, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+		}
+	}
+	if in.AggregateTo != nil {
+		in, out := &in.AggregateTo, &out.AggregateTo
+		*out = make([]AggregationTarget, len(*in))
+		copy(*out, *in)
+	}
+	if in.AccessRequests != nil {
+		in, out := &in.AccessRequests, &out.AccessRequests
+		*out = new(GroupAccessRequestPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Includes != nil {
+		in, out := &in.Includes, &out.Includes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -135,12 +450,280 @@ func (in *GroupSpec) DeepCopy() *GroupSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupStatus) DeepCopyInto(out *GroupStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConflictingRoles != nil {
+		in, out := &in.ConflictingRoles, &out.ConflictingRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConflictingClusterRoles != nil {
+		in, out := &in.ConflictingClusterRoles, &out.ConflictingClusterRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SubjectBindings != nil {
+		in, out := &in.SubjectBindings, &out.SubjectBindings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludedGroups != nil {
+		in, out := &in.IncludedGroups, &out.IncludedGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespacePermissions != nil {
+		in, out := &in.NamespacePermissions, &out.NamespacePermissions
+		*out = make([]NamespacePermissionsStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupStatus.
+func (in *GroupStatus) DeepCopy() *GroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupSubjects) DeepCopyInto(out *GroupSubjects) {
+	*out = *in
+	if in.OIDCGroups != nil {
+		in, out := &in.OIDCGroups, &out.OIDCGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GroupsPrefix != nil {
+		in, out := &in.GroupsPrefix, &out.GroupsPrefix
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupSubjects.
+func (in *GroupSubjects) DeepCopy() *GroupSubjects {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupSubjects)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LDAPSynchronisationSourceSpec) DeepCopyInto(out *LDAPSynchronisationSourceSpec) {
+	*out = *in
+	if in.RequiredGroups != nil {
+		in, out := &in.RequiredGroups, &out.RequiredGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAPSynchronisationSourceSpec.
+func (in *LDAPSynchronisationSourceSpec) DeepCopy() *LDAPSynchronisationSourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LDAPSynchronisationSourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacePermissions) DeepCopyInto(out *NamespacePermissions) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]rbacv1.PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacePermissions.
+func (in *NamespacePermissions) DeepCopy() *NamespacePermissions {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacePermissions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacePermissionsStatus) DeepCopyInto(out *NamespacePermissionsStatus) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacePermissionsStatus.
+func (in *NamespacePermissionsStatus) DeepCopy() *NamespacePermissionsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacePermissionsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacedRoleRef) DeepCopyInto(out *NamespacedRoleRef) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedRoleRef.
+func (in *NamespacedRoleRef) DeepCopy() *NamespacedRoleRef {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacedRoleRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlannedUserUpdate) DeepCopyInto(out *PlannedUserUpdate) {
+	*out = *in
+	if in.AddedGroups != nil {
+		in, out := &in.AddedGroups, &out.AddedGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RemovedGroups != nil {
+		in, out := &in.RemovedGroups, &out.RemovedGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlannedUserUpdate.
+func (in *PlannedUserUpdate) DeepCopy() *PlannedUserUpdate {
+	if in == nil {
+		return nil
+	}
+	out := new(PlannedUserUpdate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SynchronisationDeletionLimit) DeepCopyInto(out *SynchronisationDeletionLimit) {
+	*out = *in
+	if in.MaxDeletions != nil {
+		in, out := &in.MaxDeletions, &out.MaxDeletions
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxDeletionPercentage != nil {
+		in, out := &in.MaxDeletionPercentage, &out.MaxDeletionPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SynchronisationDeletionLimit.
+func (in *SynchronisationDeletionLimit) DeepCopy() *SynchronisationDeletionLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(SynchronisationDeletionLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SynchronisationPlan) DeepCopyInto(out *SynchronisationPlan) {
+	*out = *in
+	if in.Create != nil {
+		in, out := &in.Create, &out.Create
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Update != nil {
+		in, out := &in.Update, &out.Update
+		*out = make([]PlannedUserUpdate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Suspend != nil {
+		in, out := &in.Suspend, &out.Suspend
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Delete != nil {
+		in, out := &in.Delete, &out.Delete
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SynchronisationPlan.
+func (in *SynchronisationPlan) DeepCopy() *SynchronisationPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(SynchronisationPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SynchronisationSource) DeepCopyInto(out *SynchronisationSource) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -203,6 +786,11 @@ func (in *SynchronisationSourceSpec) DeepCopyInto(out *SynchronisationSourceSpec
 		*out = new(AuthentikSynchronisationSourceSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.LDAP != nil {
+		in, out := &in.LDAP, &out.LDAP
+		*out = new(LDAPSynchronisationSourceSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.GroupMappings != nil {
 		in, out := &in.GroupMappings, &out.GroupMappings
 		*out = make(map[string]string, len(*in))
@@ -219,6 +807,21 @@ func (in *SynchronisationSourceSpec) DeepCopyInto(out *SynchronisationSourceSpec
 			copy(*out, *in)
 		}
 	}
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DeletionLimit != nil {
+		in, out := &in.DeletionLimit, &out.DeletionLimit
+		*out = new(SynchronisationDeletionLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Deprovisioning != nil {
+		in, out := &in.Deprovisioning, &out.Deprovisioning
+		*out = new(DeprovisioningPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -232,12 +835,49 @@ func (in *SynchronisationSourceSpec) DeepCopy() *SynchronisationSourceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SynchronisationSourceStatus) DeepCopyInto(out *SynchronisationSourceStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastAttemptTime != nil {
+		in, out := &in.LastAttemptTime, &out.LastAttemptTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(SynchronisationPlan)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SynchronisationSourceStatus.
+func (in *SynchronisationSourceStatus) DeepCopy() *SynchronisationSourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SynchronisationSourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *User) DeepCopyInto(out *User) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -300,6 +940,28 @@ func (in *UserSpec) DeepCopyInto(out *UserSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Memberships != nil {
+		in, out := &in.Memberships, &out.Memberships
+		*out = make([]GroupMembership, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Token != nil {
+		in, out := &in.Token, &out.Token
+		*out = new(UserTokenSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Identity != nil {
+		in, out := &in.Identity, &out.Identity
+		*out = new(ExternalIdentity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServiceAccount != nil {
+		in, out := &in.ServiceAccount, &out.ServiceAccount
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -312,3 +974,83 @@ func (in *UserSpec) DeepCopy() *UserSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserStatus) DeepCopyInto(out *UserStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BoundNamespaces != nil {
+		in, out := &in.BoundNamespaces, &out.BoundNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RoleBindings != nil {
+		in, out := &in.RoleBindings, &out.RoleBindings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterRoleBindings != nil {
+		in, out := &in.ClusterRoleBindings, &out.ClusterRoleBindings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TokenExpirationTime != nil {
+		in, out := &in.TokenExpirationTime, &out.TokenExpirationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Memberships != nil {
+		in, out := &in.Memberships, &out.Memberships
+		*out = make([]GroupMembershipStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EffectiveGroups != nil {
+		in, out := &in.EffectiveGroups, &out.EffectiveGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserStatus.
+func (in *UserStatus) DeepCopy() *UserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserTokenSpec) DeepCopyInto(out *UserTokenSpec) {
+	*out = *in
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Audiences != nil {
+		in, out := &in.Audiences, &out.Audiences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserTokenSpec.
+func (in *UserTokenSpec) DeepCopy() *UserTokenSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UserTokenSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2024 Tobias Grether
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	v1alpha1 "perm8s/pkg/apis/perm8s/v1alpha1"
+	scheme "perm8s/pkg/generated/clientset/versioned/scheme"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// AccessRequestsGetter has a method to return a AccessRequestInterface.
+// A group's client should implement this interface.
+type AccessRequestsGetter interface {
+	AccessRequests(namespace string) AccessRequestInterface
+}
+
+// AccessRequestInterface has methods to work with AccessRequest resources.
+type AccessRequestInterface interface {
+	Create(ctx context.Context, accessRequest *v1alpha1.AccessRequest, opts v1.CreateOptions) (*v1alpha1.AccessRequest, error)
+	Update(ctx context.Context, accessRequest *v1alpha1.AccessRequest, opts v1.UpdateOptions) (*v1alpha1.AccessRequest, error)
+	UpdateStatus(ctx context.Context, accessRequest *v1alpha1.AccessRequest, opts v1.UpdateOptions) (*v1alpha1.AccessRequest, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.AccessRequest, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1alpha1.AccessRequestList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.AccessRequest, err error)
+	AccessRequestExpansion
+}
+
+// accessRequests implements AccessRequestInterface
+type accessRequests struct {
+	client rest.Interface
+	ns     string
+}
+
+// newAccessRequests returns a AccessRequests
+func newAccessRequests(c *Perm8sV1alpha1Client, namespace string) *accessRequests {
+	return &accessRequests{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the accessRequest, and returns the corresponding accessRequest object, and an error if there is any.
+func (c *accessRequests) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.AccessRequest, err error) {
+	result = &v1alpha1.AccessRequest{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("accessrequests").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of AccessRequests that match those selectors.
+func (c *accessRequests) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.AccessRequestList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.AccessRequestList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("accessrequests").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested accessRequests.
+func (c *accessRequests) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("accessrequests").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a accessRequest and creates it.  Returns the server's representation of the accessRequest, and an error, if there is any.
+func (c *accessRequests) Create(ctx context.Context, accessRequest *v1alpha1.AccessRequest, opts v1.CreateOptions) (result *v1alpha1.AccessRequest, err error) {
+	result = &v1alpha1.AccessRequest{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("accessrequests").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(accessRequest).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a accessRequest and updates it. Returns the server's representation of the accessRequest, and an error, if there is any.
+func (c *accessRequests) Update(ctx context.Context, accessRequest *v1alpha1.AccessRequest, opts v1.UpdateOptions) (result *v1alpha1.AccessRequest, err error) {
+	result = &v1alpha1.AccessRequest{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("accessrequests").
+		Name(accessRequest.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(accessRequest).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *accessRequests) UpdateStatus(ctx context.Context, accessRequest *v1alpha1.AccessRequest, opts v1.UpdateOptions) (result *v1alpha1.AccessRequest, err error) {
+	result = &v1alpha1.AccessRequest{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("accessrequests").
+		Name(accessRequest.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(accessRequest).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the accessRequest and deletes it. Returns an error if one occurs.
+func (c *accessRequests) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("accessrequests").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *accessRequests) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("accessrequests").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched accessRequest.
+func (c *accessRequests) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.AccessRequest, err error) {
+	result = &v1alpha1.AccessRequest{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("accessrequests").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
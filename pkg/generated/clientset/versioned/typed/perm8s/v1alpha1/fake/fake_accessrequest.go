@@ -0,0 +1,141 @@
+/*
+Copyright 2024 Tobias Grether
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+	v1alpha1 "perm8s/pkg/apis/perm8s/v1alpha1"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeAccessRequests implements AccessRequestInterface
+type FakeAccessRequests struct {
+	Fake *FakePerm8sV1alpha1
+	ns   string
+}
+
+var accessrequestsResource = v1alpha1.SchemeGroupVersion.WithResource("accessrequests")
+
+var accessrequestsKind = v1alpha1.SchemeGroupVersion.WithKind("AccessRequest")
+
+// Get takes name of the accessRequest, and returns the corresponding accessRequest object, and an error if there is any.
+func (c *FakeAccessRequests) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.AccessRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(accessrequestsResource, c.ns, name), &v1alpha1.AccessRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.AccessRequest), err
+}
+
+// List takes label and field selectors, and returns the list of AccessRequests that match those selectors.
+func (c *FakeAccessRequests) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.AccessRequestList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(accessrequestsResource, accessrequestsKind, c.ns, opts), &v1alpha1.AccessRequestList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.AccessRequestList{ListMeta: obj.(*v1alpha1.AccessRequestList).ListMeta}
+	for _, item := range obj.(*v1alpha1.AccessRequestList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested accessRequests.
+func (c *FakeAccessRequests) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(accessrequestsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a accessRequest and creates it.  Returns the server's representation of the accessRequest, and an error, if there is any.
+func (c *FakeAccessRequests) Create(ctx context.Context, accessRequest *v1alpha1.AccessRequest, opts v1.CreateOptions) (result *v1alpha1.AccessRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(accessrequestsResource, c.ns, accessRequest), &v1alpha1.AccessRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.AccessRequest), err
+}
+
+// Update takes the representation of a accessRequest and updates it. Returns the server's representation of the accessRequest, and an error, if there is any.
+func (c *FakeAccessRequests) Update(ctx context.Context, accessRequest *v1alpha1.AccessRequest, opts v1.UpdateOptions) (result *v1alpha1.AccessRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(accessrequestsResource, c.ns, accessRequest), &v1alpha1.AccessRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.AccessRequest), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeAccessRequests) UpdateStatus(ctx context.Context, accessRequest *v1alpha1.AccessRequest, opts v1.UpdateOptions) (*v1alpha1.AccessRequest, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(accessrequestsResource, "status", c.ns, accessRequest), &v1alpha1.AccessRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.AccessRequest), err
+}
+
+// Delete takes name of the accessRequest and deletes it. Returns an error if one occurs.
+func (c *FakeAccessRequests) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(accessrequestsResource, c.ns, name, opts), &v1alpha1.AccessRequest{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeAccessRequests) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(accessrequestsResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.AccessRequestList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched accessRequest.
+func (c *FakeAccessRequests) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.AccessRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(accessrequestsResource, c.ns, name, pt, data, subresources...), &v1alpha1.AccessRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.AccessRequest), err
+}
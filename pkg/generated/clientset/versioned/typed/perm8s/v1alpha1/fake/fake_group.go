@@ -101,6 +101,18 @@ func (c *FakeGroups) Update(ctx context.Context, group *v1alpha1.Group, opts v1.
 	return obj.(*v1alpha1.Group), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeGroups) UpdateStatus(ctx context.Context, group *v1alpha1.Group, opts v1.UpdateOptions) (*v1alpha1.Group, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(groupsResource, "status", c.ns, group), &v1alpha1.Group{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Group), err
+}
+
 // Delete takes name of the group and deletes it. Returns an error if one occurs.
 func (c *FakeGroups) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
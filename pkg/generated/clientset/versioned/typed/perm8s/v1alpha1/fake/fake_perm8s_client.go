@@ -29,6 +29,10 @@ type FakePerm8sV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakePerm8sV1alpha1) AccessRequests(namespace string) v1alpha1.AccessRequestInterface {
+	return &FakeAccessRequests{c, namespace}
+}
+
 func (c *FakePerm8sV1alpha1) Groups(namespace string) v1alpha1.GroupInterface {
 	return &FakeGroups{c, namespace}
 }
@@ -101,6 +101,18 @@ func (c *FakeSynchronisationSources) Update(ctx context.Context, synchronisation
 	return obj.(*v1alpha1.SynchronisationSource), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeSynchronisationSources) UpdateStatus(ctx context.Context, synchronisationSource *v1alpha1.SynchronisationSource, opts v1.UpdateOptions) (*v1alpha1.SynchronisationSource, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(synchronisationsourcesResource, "status", c.ns, synchronisationSource), &v1alpha1.SynchronisationSource{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.SynchronisationSource), err
+}
+
 // Delete takes name of the synchronisationSource and deletes it. Returns an error if one occurs.
 func (c *FakeSynchronisationSources) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
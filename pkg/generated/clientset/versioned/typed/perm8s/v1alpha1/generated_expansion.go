@@ -18,6 +18,8 @@ limitations under the License.
 
 package v1alpha1
 
+type AccessRequestExpansion interface{}
+
 type GroupExpansion interface{}
 
 type SynchronisationSourceExpansion interface{}
@@ -40,6 +40,7 @@ type GroupsGetter interface {
 type GroupInterface interface {
 	Create(ctx context.Context, group *v1alpha1.Group, opts v1.CreateOptions) (*v1alpha1.Group, error)
 	Update(ctx context.Context, group *v1alpha1.Group, opts v1.UpdateOptions) (*v1alpha1.Group, error)
+	UpdateStatus(ctx context.Context, group *v1alpha1.Group, opts v1.UpdateOptions) (*v1alpha1.Group, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.Group, error)
@@ -135,6 +136,22 @@ func (c *groups) Update(ctx context.Context, group *v1alpha1.Group, opts v1.Upda
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *groups) UpdateStatus(ctx context.Context, group *v1alpha1.Group, opts v1.UpdateOptions) (result *v1alpha1.Group, err error) {
+	result = &v1alpha1.Group{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("groups").
+		Name(group.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(group).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the group and deletes it. Returns an error if one occurs.
 func (c *groups) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -28,6 +28,7 @@ import (
 
 type Perm8sV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	AccessRequestsGetter
 	GroupsGetter
 	SynchronisationSourcesGetter
 	UsersGetter
@@ -38,6 +39,10 @@ type Perm8sV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *Perm8sV1alpha1Client) AccessRequests(namespace string) AccessRequestInterface {
+	return newAccessRequests(c, namespace)
+}
+
 func (c *Perm8sV1alpha1Client) Groups(namespace string) GroupInterface {
 	return newGroups(c, namespace)
 }
@@ -40,6 +40,7 @@ type SynchronisationSourcesGetter interface {
 type SynchronisationSourceInterface interface {
 	Create(ctx context.Context, synchronisationSource *v1alpha1.SynchronisationSource, opts v1.CreateOptions) (*v1alpha1.SynchronisationSource, error)
 	Update(ctx context.Context, synchronisationSource *v1alpha1.SynchronisationSource, opts v1.UpdateOptions) (*v1alpha1.SynchronisationSource, error)
+	UpdateStatus(ctx context.Context, synchronisationSource *v1alpha1.SynchronisationSource, opts v1.UpdateOptions) (*v1alpha1.SynchronisationSource, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.SynchronisationSource, error)
@@ -135,6 +136,22 @@ func (c *synchronisationSources) Update(ctx context.Context, synchronisationSour
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *synchronisationSources) UpdateStatus(ctx context.Context, synchronisationSource *v1alpha1.SynchronisationSource, opts v1.UpdateOptions) (result *v1alpha1.SynchronisationSource, err error) {
+	result = &v1alpha1.SynchronisationSource{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("synchronisationsources").
+		Name(synchronisationSource.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(synchronisationSource).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the synchronisationSource and deletes it. Returns an error if one occurs.
 func (c *synchronisationSources) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -40,6 +40,7 @@ type UsersGetter interface {
 type UserInterface interface {
 	Create(ctx context.Context, user *v1alpha1.User, opts v1.CreateOptions) (*v1alpha1.User, error)
 	Update(ctx context.Context, user *v1alpha1.User, opts v1.UpdateOptions) (*v1alpha1.User, error)
+	UpdateStatus(ctx context.Context, user *v1alpha1.User, opts v1.UpdateOptions) (*v1alpha1.User, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.User, error)
@@ -135,6 +136,22 @@ func (c *users) Update(ctx context.Context, user *v1alpha1.User, opts v1.UpdateO
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *users) UpdateStatus(ctx context.Context, user *v1alpha1.User, opts v1.UpdateOptions) (result *v1alpha1.User, err error) {
+	result = &v1alpha1.User{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("users").
+		Name(user.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(user).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the user and deletes it. Returns an error if one occurs.
 func (c *users) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
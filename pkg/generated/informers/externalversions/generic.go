@@ -53,6 +53,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=perm8s.tobiasgrether.com, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("accessrequests"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Perm8s().V1alpha1().AccessRequests().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("groups"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Perm8s().V1alpha1().Groups().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("synchronisationsources"):
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 Tobias Grether
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	perm8sv1alpha1 "perm8s/pkg/apis/perm8s/v1alpha1"
+	versioned "perm8s/pkg/generated/clientset/versioned"
+	internalinterfaces "perm8s/pkg/generated/informers/externalversions/internalinterfaces"
+	v1alpha1 "perm8s/pkg/generated/listers/perm8s/v1alpha1"
+	time "time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// AccessRequestInformer provides access to a shared informer and lister for
+// AccessRequests.
+type AccessRequestInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.AccessRequestLister
+}
+
+type accessRequestInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewAccessRequestInformer constructs a new informer for AccessRequest type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewAccessRequestInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredAccessRequestInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredAccessRequestInformer constructs a new informer for AccessRequest type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredAccessRequestInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.Perm8sV1alpha1().AccessRequests(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.Perm8sV1alpha1().AccessRequests(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&perm8sv1alpha1.AccessRequest{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *accessRequestInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredAccessRequestInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *accessRequestInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&perm8sv1alpha1.AccessRequest{}, f.defaultInformer)
+}
+
+func (f *accessRequestInformer) Lister() v1alpha1.AccessRequestLister {
+	return v1alpha1.NewAccessRequestLister(f.Informer().GetIndexer())
+}
@@ -24,6 +24,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// AccessRequests returns a AccessRequestInformer.
+	AccessRequests() AccessRequestInformer
 	// Groups returns a GroupInformer.
 	Groups() GroupInformer
 	// SynchronisationSources returns a SynchronisationSourceInformer.
@@ -43,6 +45,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// AccessRequests returns a AccessRequestInformer.
+func (v *version) AccessRequests() AccessRequestInformer {
+	return &accessRequestInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // Groups returns a GroupInformer.
 func (v *version) Groups() GroupInformer {
 	return &groupInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 Tobias Grether
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "perm8s/pkg/apis/perm8s/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// AccessRequestLister helps list AccessRequests.
+// All objects returned here must be treated as read-only.
+type AccessRequestLister interface {
+	// List lists all AccessRequests in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.AccessRequest, err error)
+	// AccessRequests returns an object that can list and get AccessRequests.
+	AccessRequests(namespace string) AccessRequestNamespaceLister
+	AccessRequestListerExpansion
+}
+
+// accessRequestLister implements the AccessRequestLister interface.
+type accessRequestLister struct {
+	indexer cache.Indexer
+}
+
+// NewAccessRequestLister returns a new AccessRequestLister.
+func NewAccessRequestLister(indexer cache.Indexer) AccessRequestLister {
+	return &accessRequestLister{indexer: indexer}
+}
+
+// List lists all AccessRequests in the indexer.
+func (s *accessRequestLister) List(selector labels.Selector) (ret []*v1alpha1.AccessRequest, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.AccessRequest))
+	})
+	return ret, err
+}
+
+// AccessRequests returns an object that can list and get AccessRequests.
+func (s *accessRequestLister) AccessRequests(namespace string) AccessRequestNamespaceLister {
+	return accessRequestNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// AccessRequestNamespaceLister helps list and get AccessRequests.
+// All objects returned here must be treated as read-only.
+type AccessRequestNamespaceLister interface {
+	// List lists all AccessRequests in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.AccessRequest, err error)
+	// Get retrieves the AccessRequest from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.AccessRequest, error)
+	AccessRequestNamespaceListerExpansion
+}
+
+// accessRequestNamespaceLister implements the AccessRequestNamespaceLister
+// interface.
+type accessRequestNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all AccessRequests in the indexer for a given namespace.
+func (s accessRequestNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.AccessRequest, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.AccessRequest))
+	})
+	return ret, err
+}
+
+// Get retrieves the AccessRequest from the indexer for a given namespace and name.
+func (s accessRequestNamespaceLister) Get(name string) (*v1alpha1.AccessRequest, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("accessrequest"), name)
+	}
+	return obj.(*v1alpha1.AccessRequest), nil
+}
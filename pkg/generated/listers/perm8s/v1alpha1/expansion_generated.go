@@ -18,6 +18,14 @@ limitations under the License.
 
 package v1alpha1
 
+// AccessRequestListerExpansion allows custom methods to be added to
+// AccessRequestLister.
+type AccessRequestListerExpansion interface{}
+
+// AccessRequestNamespaceListerExpansion allows custom methods to be added to
+// AccessRequestNamespaceLister.
+type AccessRequestNamespaceListerExpansion interface{}
+
 // GroupListerExpansion allows custom methods to be added to
 // GroupLister.
 type GroupListerExpansion interface{}
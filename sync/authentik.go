@@ -13,7 +13,7 @@ import (
     "slices"
 )
 
-func ComputeAuthentikUsers(ctx context.Context, source v1alpha1.SynchronisationSource, coreClient *v1.CoreV1Client) (*[]SyncUser, error) {
+func ComputeAuthentikUsers(ctx context.Context, source v1alpha1.SynchronisationSource, coreClient v1.CoreV1Interface) (*[]SyncUser, error) {
 	sourceConfig := source.Spec.Authentik
     logger := klog.FromContext(ctx).WithValues("provider", "authentik")
 	
@@ -0,0 +1,233 @@
+package sync
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/go-ldap/ldap"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+	"perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+const ldapPageSize = 500
+
+// DialLDAP opens a connection to the directory behind the given ldap:// or ldaps:// URL.
+// It is a variable so that an in-process stand-in implementing ldap.Client can be used instead of a real server.
+var DialLDAP = func(rawURL string, tlsConfig *tls.Config) (ldap.Client, error) {
+	address, useTLS, err := ldapAddress(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if useTLS {
+		return ldap.DialTLS("tcp", address, tlsConfig)
+	}
+	return ldap.Dial("tcp", address)
+}
+
+// ldapAddress returns the host and port to dial for the given ldap:// or ldaps:// URL, falling back to the default
+// port of the scheme, and whether the connection uses TLS
+func ldapAddress(rawURL string) (string, bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false, err
+	}
+
+	port := u.Port()
+
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = ldap.DefaultLdapPort
+		}
+		return net.JoinHostPort(u.Hostname(), port), false, nil
+	case "ldaps":
+		if port == "" {
+			port = ldap.DefaultLdapsPort
+		}
+		return net.JoinHostPort(u.Hostname(), port), true, nil
+	}
+
+	return "", false, fmt.Errorf("unsupported LDAP URL scheme %q", u.Scheme)
+}
+
+func ComputeLDAPUsers(ctx context.Context, source v1alpha1.SynchronisationSource, coreClient v1.CoreV1Interface) (*[]SyncUser, error) {
+	sourceConfig := source.Spec.LDAP
+	logger := klog.FromContext(ctx).WithValues("provider", "ldap")
+
+	if sourceConfig == nil {
+		return nil, errors.New("cannot sync from ldap: No LDAP configuration provided")
+	}
+
+	password := ""
+	if sourceConfig.SecretName != "" {
+		secret, err := coreClient.Secrets(source.Namespace).Get(ctx, sourceConfig.SecretName, v3.GetOptions{})
+
+		if errors2.IsNotFound(err) {
+			logger.Error(err, "Cannot sync from LDAP source, secret cannot be found", "secretName", sourceConfig.SecretName, "namespace", source.Namespace)
+			return nil, fmt.Errorf("cannot sync from LDAP source, secret %v cannot be found in namespace %v", sourceConfig.SecretName, source.Namespace)
+		}
+
+		if err != nil {
+			logger.Error(err, "Error while doing sync from LDAP source")
+			return nil, err
+		}
+
+		password = string(secret.Data["password"])
+	}
+
+	tlsConfig, err := ldapTLSConfig(ctx, source.Namespace, sourceConfig, coreClient)
+	if err != nil {
+		logger.Error(err, "Error while building TLS configuration for LDAP source")
+		return nil, err
+	}
+
+	conn, err := DialLDAP(sourceConfig.URL, tlsConfig)
+	if err != nil {
+		logger.Error(err, "Cannot connect to LDAP server", "url", sourceConfig.URL)
+		return nil, err
+	}
+	defer conn.Close()
+
+	if sourceConfig.StartTLS && !strings.HasPrefix(sourceConfig.URL, "ldaps://") {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			logger.Error(err, "StartTLS failed for LDAP server", "url", sourceConfig.URL)
+			return nil, err
+		}
+	}
+
+	if sourceConfig.BindDN != "" {
+		if err = conn.Bind(sourceConfig.BindDN, password); err != nil {
+			logger.Error(err, "Bind failed for LDAP server", "url", sourceConfig.URL, "bindDN", sourceConfig.BindDN)
+			return nil, err
+		}
+	}
+
+	return ComputeLDAPUsersFromClient(ctx, *sourceConfig, conn)
+}
+
+// ComputeLDAPUsersFromClient searches the users and groups of an already connected and bound directory
+// and resolves the group memberships of every user.
+func ComputeLDAPUsersFromClient(ctx context.Context, sourceConfig v1alpha1.LDAPSynchronisationSourceSpec, conn ldap.Client) (*[]SyncUser, error) {
+	logger := klog.FromContext(ctx).WithValues("provider", "ldap")
+
+	userFilter := withDefault(sourceConfig.UserFilter, "(objectClass=person)")
+	userNameAttribute := withDefault(sourceConfig.UserNameAttribute, "uid")
+	groupFilter := withDefault(sourceConfig.GroupFilter, "(|(objectClass=groupOfNames)(objectClass=groupOfUniqueNames)(objectClass=posixGroup)(objectClass=group))")
+	groupNameAttribute := withDefault(sourceConfig.GroupNameAttribute, "cn")
+	memberAttribute := withDefault(sourceConfig.MemberAttribute, "member")
+
+	groupResult, err := conn.SearchWithPaging(ldap.NewSearchRequest(
+		sourceConfig.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		groupFilter, []string{groupNameAttribute, memberAttribute}, nil,
+	), ldapPageSize)
+
+	if err != nil {
+		logger.Error(err, "Group search failed for LDAP server", "baseDN", sourceConfig.GroupBaseDN, "filter", groupFilter)
+		return nil, err
+	}
+
+	// members can either be referenced by their DN or by their name attribute, so we index both in lower case
+	memberships := map[string][]string{}
+	for _, entry := range groupResult.Entries {
+		groupName := entry.GetAttributeValue(groupNameAttribute)
+		if groupName == "" {
+			continue
+		}
+
+		for _, member := range entry.GetAttributeValues(memberAttribute) {
+			key := normaliseLDAPMember(member)
+			memberships[key] = append(memberships[key], groupName)
+		}
+	}
+
+	userResult, err := conn.SearchWithPaging(ldap.NewSearchRequest(
+		sourceConfig.UserBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		userFilter, []string{userNameAttribute}, nil,
+	), ldapPageSize)
+
+	if err != nil {
+		logger.Error(err, "User search failed for LDAP server", "baseDN", sourceConfig.UserBaseDN, "filter", userFilter)
+		return nil, err
+	}
+
+	allowedUsers := []SyncUser{}
+
+	for _, entry := range userResult.Entries {
+		name := entry.GetAttributeValue(userNameAttribute)
+		if name == "" {
+			logger.V(4).Info("Skipping LDAP entry without name attribute", "dn", entry.DN, "attribute", userNameAttribute)
+			continue
+		}
+
+		var groups []string
+		for _, group := range slices.Concat(memberships[normaliseLDAPMember(entry.DN)], memberships[normaliseLDAPMember(name)]) {
+			if !slices.Contains(groups, group) {
+				groups = append(groups, group)
+			}
+		}
+
+		if len(sourceConfig.RequiredGroups) > 0 && !slices.ContainsFunc(sourceConfig.RequiredGroups, func(required string) bool {
+			return slices.Contains(groups, required)
+		}) {
+			continue
+		}
+
+		allowedUsers = append(allowedUsers, SyncUser{
+			Name:   name,
+			Groups: groups,
+		})
+	}
+
+	return &allowedUsers, nil
+}
+
+func ldapTLSConfig(ctx context.Context, namespace string, sourceConfig *v1alpha1.LDAPSynchronisationSourceSpec, coreClient v1.CoreV1Interface) (*tls.Config, error) {
+	u, err := url.Parse(sourceConfig.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: sourceConfig.InsecureSkipVerify,
+	}
+
+	if sourceConfig.CASecretName == "" {
+		return tlsConfig, nil
+	}
+
+	secret, err := coreClient.Secrets(namespace).Get(ctx, sourceConfig.CASecretName, v3.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CA secret %v in namespace %v: %w", sourceConfig.CASecretName, namespace, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(secret.Data["ca.crt"]) {
+		return nil, fmt.Errorf("CA secret %v in namespace %v does not contain a valid PEM bundle in key ca.crt", sourceConfig.CASecretName, namespace)
+	}
+	tlsConfig.RootCAs = pool
+
+	return tlsConfig, nil
+}
+
+func normaliseLDAPMember(member string) string {
+	return strings.ToLower(strings.ReplaceAll(member, ", ", ","))
+}
+
+func withDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
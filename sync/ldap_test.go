@@ -0,0 +1,395 @@
+package sync
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap"
+	v2 "k8s.io/api/core/v1"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+// fakeLDAPClient serves the entries below a base DN and records the requests it receives
+type fakeLDAPClient struct {
+	ldap.Client
+
+	entries  map[string][]*ldap.Entry
+	bindErr  error
+	searches []*ldap.SearchRequest
+	pageSize uint32
+	startTLS *tls.Config
+	closed   bool
+}
+
+func (f *fakeLDAPClient) Bind(username string, password string) error {
+	return f.bindErr
+}
+
+func (f *fakeLDAPClient) StartTLS(config *tls.Config) error {
+	f.startTLS = config
+	return nil
+}
+
+func (f *fakeLDAPClient) Close() {
+	f.closed = true
+}
+
+func (f *fakeLDAPClient) SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	f.searches = append(f.searches, searchRequest)
+	f.pageSize = pagingSize
+	return &ldap.SearchResult{Entries: f.entries[searchRequest.BaseDN]}, nil
+}
+
+// newSecretClient returns a CoreV1Client that serves the given secrets of the namespace team
+func newSecretClient(t *testing.T, secrets map[string]map[string][]byte) *v1.CoreV1Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/team/secrets/")
+		data, ok := secrets[name]
+		if !ok || name == r.URL.Path {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&v2.Secret{
+			TypeMeta:   v3.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: v3.ObjectMeta{Name: name, Namespace: "team"},
+			Data:       data,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := v1.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("NewForConfig() error = %v", err)
+	}
+
+	return client
+}
+
+// newCAPEM returns a self-signed CA certificate in PEM encoding
+func newCAPEM(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "acme ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// stubDialLDAP replaces DialLDAP with one returning client for the duration of the test and records its arguments
+func stubDialLDAP(t *testing.T, client ldap.Client) (*string, **tls.Config) {
+	t.Helper()
+
+	var dialedURL string
+	var dialedTLS *tls.Config
+
+	dial := DialLDAP
+	DialLDAP = func(rawURL string, tlsConfig *tls.Config) (ldap.Client, error) {
+		dialedURL, dialedTLS = rawURL, tlsConfig
+		return client, nil
+	}
+	t.Cleanup(func() { DialLDAP = dial })
+
+	return &dialedURL, &dialedTLS
+}
+
+func TestComputeLDAPUsersFromClient(t *testing.T) {
+	users := []*ldap.Entry{
+		ldap.NewEntry("uid=jane,ou=people,dc=acme,dc=com", map[string][]string{"uid": {"jane"}}),
+		ldap.NewEntry("uid=john,ou=people,dc=acme,dc=com", map[string][]string{"uid": {"john"}}),
+		ldap.NewEntry("uid=nameless,ou=people,dc=acme,dc=com", nil),
+	}
+
+	tests := []struct {
+		name   string
+		config v1alpha1.LDAPSynchronisationSourceSpec
+		groups []*ldap.Entry
+		want   []SyncUser
+	}{
+		{
+			name:   "groups referencing their members by DN",
+			config: v1alpha1.LDAPSynchronisationSourceSpec{},
+			groups: []*ldap.Entry{
+				// DNs are compared without the spaces after separators and regardless of case
+				ldap.NewEntry("cn=admins,ou=groups,dc=acme,dc=com", map[string][]string{"cn": {"admins"}, "member": {"UID=jane, ou=people, dc=acme, dc=com"}}),
+				ldap.NewEntry("cn=developers,ou=groups,dc=acme,dc=com", map[string][]string{"cn": {"developers"}, "member": {"uid=jane,ou=people,dc=acme,dc=com", "uid=john,ou=people,dc=acme,dc=com"}}),
+			},
+			want: []SyncUser{{Name: "jane", Groups: []string{"admins", "developers"}}, {Name: "john", Groups: []string{"developers"}}},
+		},
+		{
+			name:   "posixGroups referencing their members by name",
+			config: v1alpha1.LDAPSynchronisationSourceSpec{MemberAttribute: "memberUid"},
+			groups: []*ldap.Entry{
+				ldap.NewEntry("cn=developers,ou=groups,dc=acme,dc=com", map[string][]string{"cn": {"developers"}, "memberUid": {"john"}}),
+			},
+			want: []SyncUser{{Name: "jane"}, {Name: "john", Groups: []string{"developers"}}},
+		},
+		{
+			name:   "groups without a name",
+			config: v1alpha1.LDAPSynchronisationSourceSpec{},
+			groups: []*ldap.Entry{
+				ldap.NewEntry("cn=unnamed,ou=groups,dc=acme,dc=com", map[string][]string{"member": {"uid=jane,ou=people,dc=acme,dc=com"}}),
+			},
+			want: []SyncUser{{Name: "jane"}, {Name: "john"}},
+		},
+		{
+			name:   "required groups",
+			config: v1alpha1.LDAPSynchronisationSourceSpec{RequiredGroups: []string{"admins"}},
+			groups: []*ldap.Entry{
+				ldap.NewEntry("cn=admins,ou=groups,dc=acme,dc=com", map[string][]string{"cn": {"admins"}, "member": {"uid=jane,ou=people,dc=acme,dc=com"}}),
+			},
+			want: []SyncUser{{Name: "jane", Groups: []string{"admins"}}},
+		},
+		{
+			name:   "users without the configured name attribute",
+			config: v1alpha1.LDAPSynchronisationSourceSpec{UserNameAttribute: "mail", GroupNameAttribute: "displayName"},
+			groups: []*ldap.Entry{
+				ldap.NewEntry("cn=admins,ou=groups,dc=acme,dc=com", map[string][]string{"cn": {"admins"}, "displayName": {"Admins"}, "member": {"uid=jane,ou=people,dc=acme,dc=com"}}),
+			},
+			want: []SyncUser{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			config.UserBaseDN = "ou=people,dc=acme,dc=com"
+			config.GroupBaseDN = "ou=groups,dc=acme,dc=com"
+
+			client := &fakeLDAPClient{entries: map[string][]*ldap.Entry{config.UserBaseDN: users, config.GroupBaseDN: test.groups}}
+
+			got, err := ComputeLDAPUsersFromClient(context.Background(), config, client)
+			if err != nil {
+				t.Fatalf("ComputeLDAPUsersFromClient() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, test.want) {
+				t.Errorf("ComputeLDAPUsersFromClient() = %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+}
+
+func TestComputeLDAPUsersFromClientSearches(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          v1alpha1.LDAPSynchronisationSourceSpec
+		wantGroupFilter string
+		wantGroupAttrs  []string
+		wantUserFilter  string
+		wantUserAttrs   []string
+	}{
+		{
+			name:            "defaults",
+			wantGroupFilter: "(|(objectClass=groupOfNames)(objectClass=groupOfUniqueNames)(objectClass=posixGroup)(objectClass=group))",
+			wantGroupAttrs:  []string{"cn", "member"},
+			wantUserFilter:  "(objectClass=person)",
+			wantUserAttrs:   []string{"uid"},
+		},
+		{
+			name: "configured filters and attributes",
+			config: v1alpha1.LDAPSynchronisationSourceSpec{
+				UserFilter:         "(objectClass=user)",
+				UserNameAttribute:  "sAMAccountName",
+				GroupFilter:        "(objectClass=group)",
+				GroupNameAttribute: "name",
+				MemberAttribute:    "memberUid",
+			},
+			wantGroupFilter: "(objectClass=group)",
+			wantGroupAttrs:  []string{"name", "memberUid"},
+			wantUserFilter:  "(objectClass=user)",
+			wantUserAttrs:   []string{"sAMAccountName"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			config.UserBaseDN = "ou=people,dc=acme,dc=com"
+			config.GroupBaseDN = "ou=groups,dc=acme,dc=com"
+
+			client := &fakeLDAPClient{}
+			if _, err := ComputeLDAPUsersFromClient(context.Background(), config, client); err != nil {
+				t.Fatalf("ComputeLDAPUsersFromClient() error = %v", err)
+			}
+
+			if client.pageSize != ldapPageSize {
+				t.Errorf("searched with page size %v, want %v", client.pageSize, ldapPageSize)
+			}
+			if len(client.searches) != 2 {
+				t.Fatalf("got %d searches, want 2", len(client.searches))
+			}
+
+			groups, users := client.searches[0], client.searches[1]
+			if groups.BaseDN != config.GroupBaseDN || groups.Filter != test.wantGroupFilter || !reflect.DeepEqual(groups.Attributes, test.wantGroupAttrs) {
+				t.Errorf("group search = %v %v %v, want %v %v %v", groups.BaseDN, groups.Filter, groups.Attributes, config.GroupBaseDN, test.wantGroupFilter, test.wantGroupAttrs)
+			}
+			if users.BaseDN != config.UserBaseDN || users.Filter != test.wantUserFilter || !reflect.DeepEqual(users.Attributes, test.wantUserAttrs) {
+				t.Errorf("user search = %v %v %v, want %v %v %v", users.BaseDN, users.Filter, users.Attributes, config.UserBaseDN, test.wantUserFilter, test.wantUserAttrs)
+			}
+		})
+	}
+}
+
+func TestComputeLDAPUsersBindFailure(t *testing.T) {
+	bindErr := ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	client := &fakeLDAPClient{bindErr: bindErr}
+	stubDialLDAP(t, client)
+
+	source := v1alpha1.SynchronisationSource{
+		ObjectMeta: v3.ObjectMeta{Name: "acme", Namespace: "team"},
+		Spec: v1alpha1.SynchronisationSourceSpec{LDAP: &v1alpha1.LDAPSynchronisationSourceSpec{
+			URL:        "ldap://ldap.acme.com",
+			BindDN:     "cn=perm8s,dc=acme,dc=com",
+			SecretName: "ldap-bind-password",
+		}},
+	}
+	coreClient := newSecretClient(t, map[string]map[string][]byte{"ldap-bind-password": {"password": []byte("secret")}})
+
+	if _, err := ComputeLDAPUsers(context.Background(), source, coreClient); !errors.Is(err, bindErr) {
+		t.Errorf("ComputeLDAPUsers() error = %v, want %v", err, bindErr)
+	}
+	if len(client.searches) > 0 {
+		t.Errorf("searched the directory after the bind failed")
+	}
+	if !client.closed {
+		t.Errorf("connection was not closed")
+	}
+}
+
+func TestComputeLDAPUsersTLS(t *testing.T) {
+	caPEM := newCAPEM(t)
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caPEM)
+
+	tests := []struct {
+		name         string
+		config       v1alpha1.LDAPSynchronisationSourceSpec
+		wantStartTLS bool
+		wantCA       bool
+		wantErr      bool
+	}{
+		{name: "ldaps with the system CAs", config: v1alpha1.LDAPSynchronisationSourceSpec{URL: "ldaps://ldap.acme.com"}},
+		{name: "ldaps with a CA secret", config: v1alpha1.LDAPSynchronisationSourceSpec{URL: "ldaps://ldap.acme.com:10636", CASecretName: "ldap-ca"}, wantCA: true},
+		{name: "ldaps ignores startTLS", config: v1alpha1.LDAPSynchronisationSourceSpec{URL: "ldaps://ldap.acme.com", StartTLS: true, CASecretName: "ldap-ca"}, wantCA: true},
+		{name: "startTLS with a CA secret", config: v1alpha1.LDAPSynchronisationSourceSpec{URL: "ldap://ldap.acme.com", StartTLS: true, CASecretName: "ldap-ca"}, wantStartTLS: true, wantCA: true},
+		{name: "plain ldap", config: v1alpha1.LDAPSynchronisationSourceSpec{URL: "ldap://ldap.acme.com"}},
+		{name: "missing CA secret", config: v1alpha1.LDAPSynchronisationSourceSpec{URL: "ldaps://ldap.acme.com", CASecretName: "missing"}, wantErr: true},
+		{name: "CA secret without a certificate", config: v1alpha1.LDAPSynchronisationSourceSpec{URL: "ldaps://ldap.acme.com", CASecretName: "invalid-ca"}, wantErr: true},
+	}
+
+	coreClient := newSecretClient(t, map[string]map[string][]byte{
+		"ldap-ca":    {"ca.crt": caPEM},
+		"invalid-ca": {"ca.crt": []byte("not a certificate")},
+	})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &fakeLDAPClient{}
+			dialedURL, dialedTLS := stubDialLDAP(t, client)
+
+			source := v1alpha1.SynchronisationSource{
+				ObjectMeta: v3.ObjectMeta{Name: "acme", Namespace: "team"},
+				Spec:       v1alpha1.SynchronisationSourceSpec{LDAP: &test.config},
+			}
+
+			_, err := ComputeLDAPUsers(context.Background(), source, coreClient)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ComputeLDAPUsers() error = %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				if *dialedURL != "" {
+					t.Errorf("dialed %v although the TLS configuration is invalid", *dialedURL)
+				}
+				return
+			}
+
+			if *dialedURL != test.config.URL {
+				t.Errorf("dialed %v, want %v", *dialedURL, test.config.URL)
+			}
+
+			tlsConfig := *dialedTLS
+			if tlsConfig.ServerName != "ldap.acme.com" {
+				t.Errorf("ServerName = %v, want ldap.acme.com", tlsConfig.ServerName)
+			}
+			if hasCA := tlsConfig.RootCAs != nil && tlsConfig.RootCAs.Equal(pool); hasCA != test.wantCA {
+				t.Errorf("RootCAs set from the secret = %v, want %v", hasCA, test.wantCA)
+			}
+
+			if startTLS := client.startTLS != nil; startTLS != test.wantStartTLS {
+				t.Errorf("StartTLS called = %v, want %v", startTLS, test.wantStartTLS)
+			}
+			if client.startTLS != nil && client.startTLS != tlsConfig {
+				t.Errorf("StartTLS used a different TLS configuration than the one built for the source")
+			}
+		})
+	}
+}
+
+func TestLDAPAddress(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		wantAddress string
+		wantTLS     bool
+		wantErr     bool
+	}{
+		{name: "ldap with the default port", url: "ldap://ldap.acme.com", wantAddress: "ldap.acme.com:389"},
+		{name: "ldap with a port", url: "ldap://ldap.acme.com:10389", wantAddress: "ldap.acme.com:10389"},
+		{name: "ldaps with the default port", url: "ldaps://ldap.acme.com", wantAddress: "ldap.acme.com:636", wantTLS: true},
+		{name: "ldaps with a port", url: "ldaps://ldap.acme.com:10636", wantAddress: "ldap.acme.com:10636", wantTLS: true},
+		{name: "IPv6 with the default port", url: "ldap://[::1]", wantAddress: "[::1]:389"},
+		{name: "IPv6 with a port", url: "ldaps://[::1]:10636", wantAddress: "[::1]:10636", wantTLS: true},
+		{name: "IPv4", url: "ldap://10.0.0.1", wantAddress: "10.0.0.1:389"},
+		{name: "unsupported scheme", url: "https://ldap.acme.com", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			address, useTLS, err := ldapAddress(test.url)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ldapAddress(%q) error = %v, want error %v", test.url, err, test.wantErr)
+			}
+			if address != test.wantAddress || useTLS != test.wantTLS {
+				t.Errorf("ldapAddress(%q) = %v, %v, want %v, %v", test.url, address, useTLS, test.wantAddress, test.wantTLS)
+			}
+		})
+	}
+}
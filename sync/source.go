@@ -11,7 +11,7 @@ import (
 // of all users that should have access to the cluster in this configuration.
 // Anyone who is not returned by this function but still has a User linked to them will have their User deleted.
 // The Corev1 Client is passed to the function to allow access to secrets or configmaps for configuration
-type ComputeUserFunc func(ctx context.Context, source v1alpha1.SynchronisationSource, coreClient *v1.CoreV1Client) (*[]SyncUser, error)
+type ComputeUserFunc func(ctx context.Context, source v1alpha1.SynchronisationSource, coreClient v1.CoreV1Interface) (*[]SyncUser, error)
 
 type SyncUser struct {
     Name   string   `json:"name"`
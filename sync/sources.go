@@ -3,4 +3,5 @@ package sync
 // SyncSources makes a list of all supported sync sources globally available. 
 var SyncSources = map[string]ComputeUserFunc{
 	"authentik": ComputeAuthentikUsers,
-}
\ No newline at end of file
+	"ldap":      ComputeLDAPUsers,
+}
@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"perm8s/pkg/apis/perm8s/v1alpha1"
+	listers "perm8s/pkg/generated/listers/perm8s/v1alpha1"
+)
+
+// Server answers the AdmissionReview requests of the validating webhook configuration for all perm8s resources
+type Server struct {
+	kubeclientset kubernetes.Interface
+	groupLister   listers.GroupLister
+	userLister    listers.UserLister
+	// oidcUsernamePrefix is the default prefix of the usernames of external identities, as configured for the controller
+	oidcUsernamePrefix string
+}
+
+func NewServer(kubeclientset kubernetes.Interface, groupLister listers.GroupLister, userLister listers.UserLister, oidcUsernamePrefix string) *Server {
+	return &Server{
+		kubeclientset:      kubeclientset,
+		groupLister:        groupLister,
+		userLister:         userLister,
+		oidcUsernamePrefix: oidcUsernamePrefix,
+	}
+}
+
+// Handler returns the http.Handler serving the validation endpoints of all resources
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate-users", s.serve(s.validateUser))
+	mux.HandleFunc("/validate-groups", s.serve(s.validateGroup))
+	mux.HandleFunc("/validate-synchronisationsources", s.serve(s.validateSynchronisationSource))
+	mux.HandleFunc("/validate-accessrequests", s.serve(s.validateAccessRequest))
+	return mux
+}
+
+// ListenAndServeTLS serves the webhook on the given address until the context is cancelled
+func (s *Server) ListenAndServeTLS(ctx context.Context, address string, certFile string, keyFile string) error {
+	server := &http.Server{Addr: address, Handler: s.Handler()}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	if err := server.ListenAndServeTLS(certFile, keyFile); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+type validateFunc func(ctx context.Context, request *admissionv1.AdmissionRequest) (field.ErrorList, error)
+
+func (s *Server) serve(validate validateFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := klog.FromContext(r.Context()).WithValues("path", r.URL.Path)
+
+		review := admissionv1.AdmissionReview{}
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Request == nil {
+			logger.Error(err, "Cannot decode AdmissionReview")
+			http.Error(w, "invalid AdmissionReview", http.StatusBadRequest)
+			return
+		}
+
+		response := &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+
+		errs, err := validate(r.Context(), review.Request)
+		if err != nil {
+			logger.Error(err, "Error while validating object", "kind", review.Request.Kind.Kind, "name", review.Request.Name)
+			response.Allowed = false
+			response.Result = &v3.Status{Status: v3.StatusFailure, Code: http.StatusBadRequest, Message: err.Error()}
+		} else if len(errs) > 0 {
+			statusErr := errors.NewInvalid(v1alpha1.Kind(review.Request.Kind.Kind), review.Request.Name, errs)
+			response.Allowed = false
+			response.Result = &statusErr.ErrStatus
+		}
+
+		review.Response = response
+		review.Request = nil
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			logger.Error(err, "Cannot encode AdmissionReview response")
+		}
+	}
+}
+
+func (s *Server) validateUser(ctx context.Context, request *admissionv1.AdmissionRequest) (field.ErrorList, error) {
+	user := &v1alpha1.User{}
+	if err := json.Unmarshal(request.Object.Raw, user); err != nil {
+		return nil, fmt.Errorf("cannot decode User: %w", err)
+	}
+
+	if user.Namespace == "" {
+		user.Namespace = request.Namespace
+	}
+
+	if user.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	var oldUser *v1alpha1.User
+	if request.Operation == admissionv1.Update && len(request.OldObject.Raw) > 0 {
+		oldUser = &v1alpha1.User{}
+		if err := json.Unmarshal(request.OldObject.Raw, oldUser); err != nil {
+			return nil, fmt.Errorf("cannot decode previous User: %w", err)
+		}
+	}
+
+	return ValidateUser(user, oldUser, func(namespace string, name string) (bool, error) {
+		_, err := s.groupLister.Groups(namespace).Get(name)
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}), nil
+}
+
+func (s *Server) validateGroup(ctx context.Context, request *admissionv1.AdmissionRequest) (field.ErrorList, error) {
+	group := &v1alpha1.Group{}
+	if err := json.Unmarshal(request.Object.Raw, group); err != nil {
+		return nil, fmt.Errorf("cannot decode Group: %w", err)
+	}
+
+	if group.Namespace == "" {
+		group.Namespace = request.Namespace
+	}
+
+	// the finalizer of a group that is being deleted has to be removable regardless of its spec
+	if group.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	return ValidateGroup(group), nil
+}
+
+func (s *Server) validateSynchronisationSource(ctx context.Context, request *admissionv1.AdmissionRequest) (field.ErrorList, error) {
+	source := &v1alpha1.SynchronisationSource{}
+	if err := json.Unmarshal(request.Object.Raw, source); err != nil {
+		return nil, fmt.Errorf("cannot decode SynchronisationSource: %w", err)
+	}
+
+	if source.Namespace == "" {
+		source.Namespace = request.Namespace
+	}
+
+	if source.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	return ValidateSynchronisationSource(source, func(namespace string, name string) (bool, error) {
+		_, err := s.kubeclientset.CoreV1().Secrets(namespace).Get(ctx, name, v3.GetOptions{})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}, func(namespace string, name string) (*string, error) {
+		group, err := s.groupLister.Groups(namespace).Get(name)
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil || group.Spec.Subjects == nil {
+			return nil, err
+		}
+		return group.Spec.Subjects.GroupsPrefix, nil
+	}), nil
+}
+
+func (s *Server) validateAccessRequest(ctx context.Context, request *admissionv1.AdmissionRequest) (field.ErrorList, error) {
+	accessRequest := &v1alpha1.AccessRequest{}
+	if err := json.Unmarshal(request.Object.Raw, accessRequest); err != nil {
+		return nil, fmt.Errorf("cannot decode AccessRequest: %w", err)
+	}
+
+	if accessRequest.Namespace == "" {
+		accessRequest.Namespace = request.Namespace
+	}
+
+	if accessRequest.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	var oldRequest *v1alpha1.AccessRequest
+	if request.Operation == admissionv1.Update && len(request.OldObject.Raw) > 0 {
+		oldRequest = &v1alpha1.AccessRequest{}
+		if err := json.Unmarshal(request.OldObject.Raw, oldRequest); err != nil {
+			return nil, fmt.Errorf("cannot decode previous AccessRequest: %w", err)
+		}
+	}
+
+	return ValidateAccessRequest(accessRequest, oldRequest, request.UserInfo.Username, func(namespace string, name string) ([]string, error) {
+		user, err := s.userLister.Users(namespace).Get(name)
+		if errors.IsNotFound(err) {
+			return Usernames(namespace, name, nil, s.oidcUsernamePrefix), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return Usernames(namespace, name, user, s.oidcUsernamePrefix), nil
+	}), nil
+}
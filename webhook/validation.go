@@ -0,0 +1,561 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	globpath "path"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/validation/path"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"perm8s/controller"
+	"perm8s/pkg/apis/perm8s/v1alpha1"
+	"perm8s/sync"
+)
+
+// minimumTokenTTL is the shortest lifetime the API server accepts for a TokenRequest
+const minimumTokenTTL = 10 * time.Minute
+
+// ExistsFunc reports whether an object with the given name exists in the given namespace
+type ExistsFunc func(namespace string, name string) (bool, error)
+
+// GroupsPrefixFunc returns the groups prefix the Group with the given name sets for its subjects, or nil if it does
+// not set one or does not exist
+type GroupsPrefixFunc func(namespace string, name string) (*string, error)
+
+// UsernamesFunc returns the usernames the User with the given name authenticates as against the API server
+type UsernamesFunc func(namespace string, name string) ([]string, error)
+
+// Usernames returns the usernames the user authenticates as, which are the username of its ServiceAccount and the
+// username of its external identity with the prefix applied. The ServiceAccount is included for users that do not exist
+// (nil), as its name is known regardless
+func Usernames(namespace string, name string, user *v1alpha1.User, defaultUsernamePrefix string) []string {
+	var usernames []string
+
+	if user == nil || user.Spec.ServiceAccount == nil || *user.Spec.ServiceAccount {
+		usernames = append(usernames, fmt.Sprintf("system:serviceaccount:%v:%v", namespace, name))
+	}
+
+	if user != nil && user.Spec.Identity != nil && user.Spec.Identity.Username != "" {
+		prefix := defaultUsernamePrefix
+		if user.Spec.Identity.UsernamePrefix != nil {
+			prefix = *user.Spec.Identity.UsernamePrefix
+		}
+		usernames = append(usernames, prefix+user.Spec.Identity.Username)
+	}
+
+	return usernames
+}
+
+// ValidateUser checks the memberships and token configuration of a User. Group references of users that are
+// managed by a SynchronisationSource are not checked, as the groups of an external source may be created later on.
+// On updates, only memberships that are not part of oldUser are checked for existence.
+// Time-bound memberships may overlap with GroupMemberships and with each other, f.e. to grant a group in several windows.
+func ValidateUser(user *v1alpha1.User, oldUser *v1alpha1.User, groupExists ExistsFunc) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	// the name is put into the labels of the objects of the user
+	for _, msg := range validation.IsValidLabelValue(user.Name) {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), user.Name, msg))
+	}
+
+	managedBySource := false
+	for _, owner := range user.OwnerReferences {
+		if owner.Kind == "SynchronisationSource" {
+			managedBySource = true
+		}
+	}
+
+	checkGroup := func(path *field.Path, groupName string, existing bool) {
+		// the bindings of a membership are named after the user and the group, so the combination has to be a valid name as well
+		for _, msg := range validation.IsDNS1123Subdomain(controller.MembershipBindingName(user.Name, groupName)) {
+			errs = append(errs, field.Invalid(path, groupName, "binding name for this membership is invalid: "+msg))
+		}
+
+		if managedBySource || existing {
+			return
+		}
+
+		exists, err := groupExists(user.Namespace, groupName)
+		if err != nil {
+			errs = append(errs, field.InternalError(path, err))
+		} else if !exists {
+			errs = append(errs, field.NotFound(path, groupName))
+		}
+	}
+
+	for i, groupName := range user.Spec.GroupMemberships {
+		path := specPath.Child("groupMemberships").Index(i)
+
+		if groupName == "" {
+			errs = append(errs, field.Required(path, "group name must not be empty"))
+			continue
+		}
+
+		if slices.Index(user.Spec.GroupMemberships, groupName) != i {
+			errs = append(errs, field.Duplicate(path, groupName))
+			continue
+		}
+
+		checkGroup(path, groupName, oldUser != nil && slices.Contains(oldUser.Spec.GroupMemberships, groupName))
+	}
+
+	for i, membership := range user.Spec.Memberships {
+		path := specPath.Child("memberships").Index(i)
+
+		if membership.Group == "" {
+			errs = append(errs, field.Required(path.Child("group"), "group name must not be empty"))
+			continue
+		}
+
+		if membership.NotBefore != nil && membership.ExpiresAt != nil && !membership.ExpiresAt.After(membership.NotBefore.Time) {
+			errs = append(errs, field.Invalid(path.Child("expiresAt"), membership.ExpiresAt.String(), "must be after notBefore"))
+		}
+
+		existing := oldUser != nil && slices.ContainsFunc(oldUser.Spec.Memberships, func(old v1alpha1.GroupMembership) bool {
+			return old.Group == membership.Group
+		})
+		checkGroup(path.Child("group"), membership.Group, existing)
+	}
+
+	if user.Spec.Token != nil && user.Spec.Token.TTL != nil && user.Spec.Token.TTL.Duration < minimumTokenTTL {
+		errs = append(errs, field.Invalid(specPath.Child("token", "ttl"), user.Spec.Token.TTL.Duration.String(), fmt.Sprintf("must be at least %v", minimumTokenTTL)))
+	}
+
+	if identity := user.Spec.Identity; identity != nil {
+		if identity.Username == "" && len(identity.Groups) == 0 {
+			errs = append(errs, field.Required(specPath.Child("identity"), "either a username or groups are required"))
+		}
+
+		// the default prefix of the controller may be empty, so the values have to be safe without it
+		if prefix := valueOrEmpty(identity.UsernamePrefix); identity.Username != "" && controller.IsReservedSubject(prefix, identity.Username) {
+			errs = append(errs, field.Forbidden(specPath.Child("identity", "username"), fmt.Sprintf("%q is reserved by the API server", prefix+identity.Username)))
+		}
+
+		for i, group := range identity.Groups {
+			if group == "" {
+				errs = append(errs, field.Required(specPath.Child("identity", "groups").Index(i), "group must not be empty"))
+			}
+
+			if prefix := valueOrEmpty(identity.GroupsPrefix); controller.IsReservedSubject(prefix, group) {
+				errs = append(errs, field.Forbidden(specPath.Child("identity", "groups").Index(i), fmt.Sprintf("%q is reserved by the API server", prefix+group)))
+			}
+		}
+	}
+
+	// bindings without any subject would not grant anything
+	if user.Spec.ServiceAccount != nil && !*user.Spec.ServiceAccount && user.Spec.Identity == nil {
+		errs = append(errs, field.Required(specPath.Child("identity"), "users without a ServiceAccount need an identity"))
+	}
+
+	return errs
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+// ValidateGroup checks that a Group can be rendered into a valid ClusterRole and targets at least one namespace
+func ValidateGroup(group *v1alpha1.Group) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	for _, msg := range validation.IsDNS1123Subdomain(group.Name) {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), group.Name, msg))
+	}
+
+	// the name is put into the labels of the objects of the group
+	for _, msg := range validation.IsValidLabelValue(group.Name) {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), group.Name, msg))
+	}
+
+	if len(group.Spec.Permissions) == 0 && len(group.Spec.ClusterPermissions) == 0 && len(group.Spec.AggregationLabels) == 0 && len(group.Spec.ClusterRoleRefs) == 0 && len(group.Spec.RoleRefs) == 0 && len(group.Spec.NamespacePermissions) == 0 && len(group.Spec.Includes) == 0 {
+		errs = append(errs, field.Required(specPath.Child("permissions"), "at least one rule, role reference or included group is required"))
+	}
+
+	errs = append(errs, validateRules(group.Spec.Permissions, group.Spec.ClusterGroup, specPath.Child("permissions"))...)
+	errs = append(errs, validateRules(group.Spec.ClusterPermissions, true, specPath.Child("clusterPermissions"))...)
+
+	if !group.Spec.ClusterGroup && len(group.Spec.Namespaces) == 0 && group.Spec.NamespaceSelector == nil && (len(group.Spec.Permissions) > 0 || len(group.Spec.AggregationLabels) > 0 || len(group.Spec.ClusterRoleRefs) > 0) {
+		errs = append(errs, field.Required(specPath.Child("namespaces"), "namespaced groups need at least one namespace"))
+	}
+
+	for i, matchLabels := range group.Spec.AggregationLabels {
+		labelsPath := specPath.Child("aggregationLabels").Index(i)
+
+		if len(matchLabels) == 0 {
+			errs = append(errs, field.Required(labelsPath, "an empty selector would aggregate every ClusterRole"))
+		}
+
+		errs = append(errs, metav1validation.ValidateLabels(matchLabels, labelsPath)...)
+	}
+
+	// the aggregation controller only fills in the rules of ClusterRoles
+	if group.Spec.NamespacedRoles && len(group.Spec.AggregationLabels) > 0 {
+		errs = append(errs, field.Invalid(specPath.Child("namespacedRoles"), group.Spec.NamespacedRoles, "aggregated groups cannot use namespaced Roles"))
+	}
+
+	// the ClusterRole holding the permissions of an aggregated group is selected by the name of the group
+	if len(group.Spec.AggregationLabels) > 0 && len(group.Spec.Permissions) > 0 {
+		for _, msg := range validation.IsValidLabelValue(group.Name) {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), group.Name, "aggregated groups with permissions need a name that is a valid label value: "+msg))
+		}
+	}
+
+	for i, clusterRoleName := range group.Spec.ClusterRoleRefs {
+		for _, msg := range path.IsValidPathSegmentName(clusterRoleName) {
+			errs = append(errs, field.Invalid(specPath.Child("clusterRoleRefs").Index(i), clusterRoleName, msg))
+		}
+	}
+
+	for i, roleRef := range group.Spec.RoleRefs {
+		refPath := specPath.Child("roleRefs").Index(i)
+
+		for _, msg := range validation.IsDNS1123Label(roleRef.Namespace) {
+			errs = append(errs, field.Invalid(refPath.Child("namespace"), roleRef.Namespace, msg))
+		}
+
+		if roleRef.Name == "" {
+			errs = append(errs, field.Required(refPath.Child("name"), "the name of the Role is required"))
+		}
+
+		for _, msg := range path.IsValidPathSegmentName(roleRef.Name) {
+			errs = append(errs, field.Invalid(refPath.Child("name"), roleRef.Name, msg))
+		}
+	}
+
+	errs = append(errs, validateNamespaces(group.Spec.Namespaces, group.Spec.NamespaceSelector, specPath)...)
+
+	for i, permissions := range group.Spec.NamespacePermissions {
+		permissionsPath := specPath.Child("namespacePermissions").Index(i)
+
+		// the name is used as label value of the rendered ClusterRole
+		for _, msg := range validation.IsDNS1123Label(permissions.Name) {
+			errs = append(errs, field.Invalid(permissionsPath.Child("name"), permissions.Name, msg))
+		}
+
+		if slices.ContainsFunc(group.Spec.NamespacePermissions[:i], func(other v1alpha1.NamespacePermissions) bool {
+			return other.Name == permissions.Name
+		}) {
+			errs = append(errs, field.Duplicate(permissionsPath.Child("name"), permissions.Name))
+		}
+
+		if len(permissions.Namespaces) == 0 && permissions.NamespaceSelector == nil {
+			errs = append(errs, field.Required(permissionsPath.Child("namespaces"), "either namespaces or a namespaceSelector are required"))
+		}
+
+		errs = append(errs, validateNamespaces(permissions.Namespaces, permissions.NamespaceSelector, permissionsPath)...)
+
+		if (len(permissions.Permissions) == 0) == (permissions.ClusterRoleRef == "") {
+			errs = append(errs, field.Required(permissionsPath.Child("permissions"), "exactly one of permissions or clusterRoleRef is required"))
+		}
+
+		errs = append(errs, validateRules(permissions.Permissions, false, permissionsPath.Child("permissions"))...)
+
+		if permissions.ClusterRoleRef != "" {
+			for _, msg := range path.IsValidPathSegmentName(permissions.ClusterRoleRef) {
+				errs = append(errs, field.Invalid(permissionsPath.Child("clusterRoleRef"), permissions.ClusterRoleRef, msg))
+			}
+		}
+	}
+
+	for i, included := range group.Spec.Includes {
+		path := specPath.Child("includes").Index(i)
+
+		for _, msg := range validation.IsDNS1123Subdomain(included) {
+			errs = append(errs, field.Invalid(path, included, msg))
+		}
+
+		if included == group.Name {
+			errs = append(errs, field.Invalid(path, included, "a group cannot include itself"))
+		}
+
+		if slices.Contains(group.Spec.Includes[:i], included) {
+			errs = append(errs, field.Duplicate(path, included))
+		}
+	}
+
+	if subjects := group.Spec.Subjects; subjects != nil {
+		for i, claim := range subjects.OIDCGroups {
+			path := specPath.Child("subjects", "oidcGroups").Index(i)
+
+			if claim == "" {
+				errs = append(errs, field.Required(path, "group must not be empty"))
+			}
+
+			if slices.Contains(subjects.OIDCGroups[:i], claim) {
+				errs = append(errs, field.Duplicate(path, claim))
+			}
+
+			if prefix := valueOrEmpty(subjects.GroupsPrefix); controller.IsReservedSubject(prefix, claim) {
+				errs = append(errs, field.Forbidden(path, fmt.Sprintf("%q is reserved by the API server", prefix+claim)))
+			}
+		}
+	}
+
+	if policy := group.Spec.AccessRequests; policy != nil {
+		path := specPath.Child("accessRequests")
+
+		if policy.ApproverGroup == "" {
+			errs = append(errs, field.Required(path.Child("approverGroup"), "an approver group is required"))
+		}
+
+		for _, msg := range validation.IsDNS1123Subdomain(policy.ApproverGroup) {
+			errs = append(errs, field.Invalid(path.Child("approverGroup"), policy.ApproverGroup, msg))
+		}
+
+		if policy.MaxDuration != nil && policy.MaxDuration.Duration <= 0 {
+			errs = append(errs, field.Invalid(path.Child("maxDuration"), policy.MaxDuration.Duration.String(), "has to be positive"))
+		}
+	}
+
+	return errs
+}
+
+// validateRules checks the rules of a ClusterRole. Non-resource URLs only take effect when bound cluster wide
+func validateRules(rules []rbacv1.PolicyRule, clusterScoped bool, rulesPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	for i, rule := range rules {
+		path := rulesPath.Index(i)
+
+		if len(rule.Verbs) == 0 {
+			errs = append(errs, field.Required(path.Child("verbs"), "at least one verb is required"))
+		}
+
+		if len(rule.Resources) == 0 && len(rule.NonResourceURLs) == 0 {
+			errs = append(errs, field.Required(path, "either resources or nonResourceURLs are required"))
+		}
+
+		if len(rule.Resources) > 0 && len(rule.NonResourceURLs) > 0 {
+			errs = append(errs, field.Invalid(path, rule.NonResourceURLs, "resources and nonResourceURLs are mutually exclusive"))
+		}
+
+		if len(rule.Resources) > 0 && len(rule.APIGroups) == 0 {
+			errs = append(errs, field.Required(path.Child("apiGroups"), "apiGroups are required for resource rules"))
+		}
+
+		if len(rule.NonResourceURLs) > 0 && !clusterScoped {
+			errs = append(errs, field.Invalid(path.Child("nonResourceURLs"), rule.NonResourceURLs, "nonResourceURLs can only be granted by cluster groups or through clusterPermissions"))
+		}
+	}
+
+	return errs
+}
+
+// validateNamespaces checks namespace names and patterns together with their label selector
+func validateNamespaces(namespaces []string, selector *metav1.LabelSelector, parentPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	for i, namespace := range namespaces {
+		// patterns are matched against the names of existing namespaces, see path.Match for their syntax
+		if strings.ContainsAny(namespace, "*?[") {
+			if _, err := globpath.Match(namespace, ""); err != nil {
+				errs = append(errs, field.Invalid(parentPath.Child("namespaces").Index(i), namespace, "invalid pattern: "+err.Error()))
+			}
+			continue
+		}
+
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, field.Invalid(parentPath.Child("namespaces").Index(i), namespace, msg))
+		}
+	}
+
+	if selector != nil {
+		errs = append(errs, metav1validation.ValidateLabelSelector(selector, metav1validation.LabelSelectorValidationOptions{}, parentPath.Child("namespaceSelector"))...)
+	}
+
+	return errs
+}
+
+// ValidateAccessRequest checks an AccessRequest and authenticates its approval. Only the User named in spec.approvedBy
+// may set or change it, through its ServiceAccount or its external identity. Whether that User is a member of the
+// approver group is checked by the controller. The spec cannot be changed anymore once the request was granted.
+func ValidateAccessRequest(request *v1alpha1.AccessRequest, oldRequest *v1alpha1.AccessRequest, requester string, usernames UsernamesFunc) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if request.Spec.User == "" {
+		errs = append(errs, field.Required(specPath.Child("user"), "the requesting user is required"))
+	}
+
+	if request.Spec.Group == "" {
+		errs = append(errs, field.Required(specPath.Child("group"), "the requested group is required"))
+	}
+
+	if request.Spec.Duration.Duration <= 0 {
+		errs = append(errs, field.Invalid(specPath.Child("duration"), request.Spec.Duration.Duration.String(), "has to be positive"))
+	}
+
+	if oldRequest != nil {
+		granted := oldRequest.Status.Phase == v1alpha1.AccessRequestActive || oldRequest.Status.Phase == v1alpha1.AccessRequestExpired
+		if granted && !reflect.DeepEqual(request.Spec, oldRequest.Spec) {
+			errs = append(errs, field.Forbidden(specPath, "the spec cannot be changed once the request was granted"))
+		}
+	}
+
+	approvedBy := request.Spec.ApprovedBy
+	if approvedBy != "" && (oldRequest == nil || oldRequest.Spec.ApprovedBy != approvedBy) {
+		path := specPath.Child("approvedBy")
+
+		if approvedBy == request.Spec.User {
+			errs = append(errs, field.Forbidden(path, "users cannot approve their own access requests"))
+		} else if approvers, err := usernames(request.Namespace, approvedBy); err != nil {
+			errs = append(errs, field.InternalError(path, err))
+		} else if !slices.Contains(approvers, requester) {
+			errs = append(errs, field.Forbidden(path, fmt.Sprintf("can only be set by %v", strings.Join(approvers, " or "))))
+		}
+	}
+
+	return errs
+}
+
+// ValidateSynchronisationSource checks that exactly the configuration block matching the type is set
+// and that all referenced secrets exist. In groupSubjects mode, the keys of the GroupMappings are bound with the groups
+// prefix of the Group they map to
+func ValidateSynchronisationSource(source *v1alpha1.SynchronisationSource, secretExists ExistsFunc, groupsPrefix GroupsPrefixFunc) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if _, ok := sync.SyncSources[source.Spec.Type]; !ok {
+		errs = append(errs, field.NotSupported(specPath.Child("type"), source.Spec.Type, []string{"authentik", "ldap"}))
+	}
+
+	if source.Spec.Authentik != nil && source.Spec.Type != "authentik" {
+		errs = append(errs, field.Forbidden(specPath.Child("authentik"), "may only be set for sources of type authentik"))
+	}
+
+	if source.Spec.LDAP != nil && source.Spec.Type != "ldap" {
+		errs = append(errs, field.Forbidden(specPath.Child("ldap"), "may only be set for sources of type ldap"))
+	}
+
+	requireSecret := func(path *field.Path, name string) {
+		exists, err := secretExists(source.Namespace, name)
+		if err != nil {
+			errs = append(errs, field.InternalError(path, err))
+		} else if !exists {
+			errs = append(errs, field.NotFound(path, name))
+		}
+	}
+
+	switch source.Spec.Type {
+	case "authentik":
+		config := source.Spec.Authentik
+		path := specPath.Child("authentik")
+
+		if config == nil {
+			errs = append(errs, field.Required(path, "required for sources of type authentik"))
+			break
+		}
+
+		if config.URL == "" {
+			errs = append(errs, field.Required(path.Child("url"), ""))
+		}
+
+		if config.Scheme != "http" && config.Scheme != "https" {
+			errs = append(errs, field.NotSupported(path.Child("scheme"), config.Scheme, []string{"http", "https"}))
+		}
+
+		if config.SecretName == "" {
+			errs = append(errs, field.Required(path.Child("secretName"), ""))
+		} else {
+			requireSecret(path.Child("secretName"), config.SecretName)
+		}
+	case "ldap":
+		config := source.Spec.LDAP
+		path := specPath.Child("ldap")
+
+		if config == nil {
+			errs = append(errs, field.Required(path, "required for sources of type ldap"))
+			break
+		}
+
+		ldapURL, err := url.Parse(config.URL)
+		if err != nil || (ldapURL.Scheme != "ldap" && ldapURL.Scheme != "ldaps") || ldapURL.Host == "" {
+			errs = append(errs, field.Invalid(path.Child("url"), config.URL, "must be an ldap:// or ldaps:// URL"))
+		} else if config.StartTLS && ldapURL.Scheme == "ldaps" {
+			errs = append(errs, field.Invalid(path.Child("startTLS"), config.StartTLS, "cannot be used with ldaps:// URLs"))
+		}
+
+		if config.UserBaseDN == "" {
+			errs = append(errs, field.Required(path.Child("userBaseDN"), ""))
+		}
+
+		if config.GroupBaseDN == "" {
+			errs = append(errs, field.Required(path.Child("groupBaseDN"), ""))
+		}
+
+		if config.BindDN != "" && config.SecretName == "" {
+			errs = append(errs, field.Required(path.Child("secretName"), "required when bindDN is set"))
+		}
+
+		if config.SecretName != "" {
+			requireSecret(path.Child("secretName"), config.SecretName)
+		}
+
+		if config.CASecretName != "" {
+			requireSecret(path.Child("caSecretName"), config.CASecretName)
+		}
+	}
+
+	for key, groupName := range source.Spec.GroupMappings {
+		if key == "" && source.Spec.Target == v1alpha1.SyncTargetGroupSubjects {
+			errs = append(errs, field.Required(specPath.Child("groupMappings"), "group claims must not be empty"))
+		}
+
+		if source.Spec.Target == v1alpha1.SyncTargetGroupSubjects {
+			prefix, err := groupsPrefix(source.Namespace, groupName)
+			if err != nil {
+				errs = append(errs, field.InternalError(specPath.Child("groupMappings").Key(key), err))
+			} else if controller.IsReservedSubject(valueOrEmpty(prefix), key) {
+				errs = append(errs, field.Forbidden(specPath.Child("groupMappings").Key(key), fmt.Sprintf("%q is reserved by the API server", valueOrEmpty(prefix)+key)))
+			}
+		}
+
+		for _, msg := range validation.IsDNS1123Subdomain(groupName) {
+			errs = append(errs, field.Invalid(specPath.Child("groupMappings").Key(key), groupName, msg))
+		}
+	}
+
+	if source.Spec.DefaultGroups != nil {
+		for i, groupName := range *source.Spec.DefaultGroups {
+			for _, msg := range validation.IsDNS1123Subdomain(groupName) {
+				errs = append(errs, field.Invalid(specPath.Child("defaultGroups").Index(i), groupName, msg))
+			}
+		}
+	}
+
+	if source.Spec.Schedule != "" {
+		if _, err := cron.ParseStandard(source.Spec.Schedule); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("schedule"), source.Spec.Schedule, err.Error()))
+		}
+	}
+
+	if deprovisioning := source.Spec.Deprovisioning; deprovisioning != nil && deprovisioning.DeleteAfter != nil {
+		path := specPath.Child("deprovisioning", "deleteAfter")
+		if deprovisioning.Policy != v1alpha1.DeprovisioningSuspendThenDelete {
+			errs = append(errs, field.Forbidden(path, "may only be set for the suspendThenDelete policy"))
+		} else if deprovisioning.DeleteAfter.Duration <= 0 {
+			errs = append(errs, field.Invalid(path, deprovisioning.DeleteAfter.Duration.String(), "must be positive"))
+		}
+	}
+
+	if source.Spec.SyncInterval != nil && source.Spec.SyncInterval.Duration <= 0 {
+		errs = append(errs, field.Invalid(specPath.Child("syncInterval"), source.Spec.SyncInterval.Duration.String(), "must be positive"))
+	}
+
+	return errs
+}
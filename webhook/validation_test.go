@@ -0,0 +1,282 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	v4 "k8s.io/api/rbac/v1"
+	v3 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"perm8s/pkg/apis/perm8s/v1alpha1"
+)
+
+func TestValidateAccessRequestApprover(t *testing.T) {
+	prefix := "oidc:"
+	serviceAccount := false
+
+	users := map[string]*v1alpha1.User{
+		"alice": {Spec: v1alpha1.UserSpec{}},
+		"bob": {Spec: v1alpha1.UserSpec{
+			ServiceAccount: &serviceAccount,
+			Identity:       &v1alpha1.ExternalIdentity{Username: "bob@example.com"},
+		}},
+		"carol": {Spec: v1alpha1.UserSpec{
+			Identity: &v1alpha1.ExternalIdentity{Username: "carol@example.com", UsernamePrefix: &prefix},
+		}},
+	}
+
+	usernames := func(namespace string, name string) ([]string, error) {
+		return Usernames(namespace, name, users[name], "sso:"), nil
+	}
+
+	tests := []struct {
+		name       string
+		approvedBy string
+		requester  string
+		valid      bool
+	}{
+		{name: "ServiceAccount of the approver", approvedBy: "alice", requester: "system:serviceaccount:team:alice", valid: true},
+		{name: "ServiceAccount of another user", approvedBy: "alice", requester: "system:serviceaccount:team:jane", valid: false},
+		{name: "identity with the default prefix", approvedBy: "bob", requester: "sso:bob@example.com", valid: true},
+		{name: "identity without the prefix", approvedBy: "bob", requester: "bob@example.com", valid: false},
+		{name: "disabled ServiceAccount", approvedBy: "bob", requester: "system:serviceaccount:team:bob", valid: false},
+		{name: "identity with its own prefix", approvedBy: "carol", requester: "oidc:carol@example.com", valid: true},
+		{name: "ServiceAccount next to an identity", approvedBy: "carol", requester: "system:serviceaccount:team:carol", valid: true},
+		{name: "ServiceAccount of a missing user", approvedBy: "dave", requester: "system:serviceaccount:team:dave", valid: true},
+		{name: "self approval", approvedBy: "jane", requester: "system:serviceaccount:team:jane", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := &v1alpha1.AccessRequest{
+				ObjectMeta: v3.ObjectMeta{Name: "request", Namespace: "team"},
+				Spec: v1alpha1.AccessRequestSpec{
+					User:       "jane",
+					Group:      "admins",
+					Duration:   v3.Duration{Duration: time.Hour},
+					ApprovedBy: test.approvedBy,
+				},
+			}
+
+			errs := ValidateAccessRequest(request, nil, test.requester, usernames)
+			if valid := len(errs) == 0; valid != test.valid {
+				t.Errorf("ValidateAccessRequest() = %v, want valid %v", errs, test.valid)
+			}
+		})
+	}
+}
+
+func TestValidateUserReservedIdentity(t *testing.T) {
+	empty := ""
+	prefix := "oidc:"
+	reserved := "system:"
+
+	tests := []struct {
+		name     string
+		identity v1alpha1.ExternalIdentity
+		valid    bool
+	}{
+		{name: "regular identity", identity: v1alpha1.ExternalIdentity{Username: "alice@example.com", Groups: []string{"platform"}}, valid: true},
+		{name: "reserved group", identity: v1alpha1.ExternalIdentity{Groups: []string{"system:authenticated"}}, valid: false},
+		{name: "reserved group with empty prefix", identity: v1alpha1.ExternalIdentity{Groups: []string{"system:masters"}, GroupsPrefix: &empty}, valid: false},
+		{name: "reserved group behind a prefix", identity: v1alpha1.ExternalIdentity{Groups: []string{"system:authenticated"}, GroupsPrefix: &prefix}, valid: true},
+		{name: "reserved username", identity: v1alpha1.ExternalIdentity{Username: "system:admin"}, valid: false},
+		{name: "prefix turning into a reserved username", identity: v1alpha1.ExternalIdentity{Username: "admin", UsernamePrefix: &reserved}, valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := &v1alpha1.User{
+				ObjectMeta: v3.ObjectMeta{Name: "alice", Namespace: "team"},
+				Spec:       v1alpha1.UserSpec{Identity: &test.identity},
+			}
+
+			errs := ValidateUser(user, nil, func(string, string) (bool, error) { return true, nil })
+			if valid := len(errs) == 0; valid != test.valid {
+				t.Errorf("ValidateUser() = %v, want valid %v", errs, test.valid)
+			}
+		})
+	}
+}
+
+func TestValidateGroupReservedSubjects(t *testing.T) {
+	empty := ""
+	prefix := "oidc:"
+
+	tests := []struct {
+		name     string
+		subjects v1alpha1.GroupSubjects
+		valid    bool
+	}{
+		{name: "regular group", subjects: v1alpha1.GroupSubjects{OIDCGroups: []string{"platform"}}, valid: true},
+		{name: "all authenticated principals", subjects: v1alpha1.GroupSubjects{OIDCGroups: []string{"system:authenticated"}}, valid: false},
+		{name: "all ServiceAccounts with empty prefix", subjects: v1alpha1.GroupSubjects{OIDCGroups: []string{"system:serviceaccounts"}, GroupsPrefix: &empty}, valid: false},
+		{name: "reserved name behind a prefix", subjects: v1alpha1.GroupSubjects{OIDCGroups: []string{"system:authenticated"}, GroupsPrefix: &prefix}, valid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			group := &v1alpha1.Group{
+				ObjectMeta: v3.ObjectMeta{Name: "platform", Namespace: "team"},
+				Spec: v1alpha1.GroupSpec{
+					ClusterGroup:    true,
+					ClusterRoleRefs: []string{"view"},
+					Subjects:        &test.subjects,
+				},
+			}
+
+			errs := ValidateGroup(group)
+			if valid := len(errs) == 0; valid != test.valid {
+				t.Errorf("ValidateGroup() = %v, want valid %v", errs, test.valid)
+			}
+		})
+	}
+}
+
+func TestValidateSynchronisationSourceReservedMappings(t *testing.T) {
+	prefix := "oidc:"
+	reserved := "system:"
+
+	prefixes := map[string]*string{"platform": &prefix, "admins": &reserved}
+	groupsPrefix := func(namespace string, name string) (*string, error) {
+		return prefixes[name], nil
+	}
+
+	tests := []struct {
+		name      string
+		claim     string
+		groupName string
+		valid     bool
+	}{
+		{name: "regular claim", claim: "platform-team", groupName: "developers", valid: true},
+		{name: "reserved claim", claim: "system:masters", groupName: "developers", valid: false},
+		{name: "reserved claim behind the prefix of the group", claim: "system:masters", groupName: "platform", valid: true},
+		{name: "prefix of the group turning into a reserved name", claim: "masters", groupName: "admins", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := &v1alpha1.SynchronisationSource{
+				ObjectMeta: v3.ObjectMeta{Name: "idp", Namespace: "team"},
+				Spec: v1alpha1.SynchronisationSourceSpec{
+					Target:        v1alpha1.SyncTargetGroupSubjects,
+					GroupMappings: map[string]string{test.claim: test.groupName},
+				},
+			}
+
+			var forbidden field.ErrorList
+			for _, err := range ValidateSynchronisationSource(source, func(string, string) (bool, error) { return true, nil }, groupsPrefix) {
+				if err.Type == field.ErrorTypeForbidden {
+					forbidden = append(forbidden, err)
+				}
+			}
+
+			if valid := len(forbidden) == 0; valid != test.valid {
+				t.Errorf("ValidateSynchronisationSource() = %v, want valid %v", forbidden, test.valid)
+			}
+		})
+	}
+}
+
+func TestValidateUser(t *testing.T) {
+	serviceAccount := false
+	notBefore := v3.NewTime(time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC))
+	expiresAt := v3.NewTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	groupExists := func(namespace string, name string) (bool, error) {
+		return name == "developers", nil
+	}
+
+	tests := []struct {
+		name     string
+		userName string
+		user     v1alpha1.User
+		old      *v1alpha1.User
+		source   bool
+		valid    bool
+	}{
+		{name: "existing group", user: v1alpha1.User{Spec: v1alpha1.UserSpec{GroupMemberships: []string{"developers"}}}, valid: true},
+		{name: "missing group", user: v1alpha1.User{Spec: v1alpha1.UserSpec{GroupMemberships: []string{"admins"}}}, valid: false},
+		{name: "missing group of a source", user: v1alpha1.User{Spec: v1alpha1.UserSpec{GroupMemberships: []string{"admins"}}}, source: true, valid: true},
+		{
+			name:  "missing group that was referenced before",
+			user:  v1alpha1.User{Spec: v1alpha1.UserSpec{GroupMemberships: []string{"admins"}}},
+			old:   &v1alpha1.User{Spec: v1alpha1.UserSpec{GroupMemberships: []string{"admins"}}},
+			valid: true,
+		},
+		{name: "empty group", user: v1alpha1.User{Spec: v1alpha1.UserSpec{GroupMemberships: []string{""}}}, valid: false},
+		{name: "duplicate group", user: v1alpha1.User{Spec: v1alpha1.UserSpec{GroupMemberships: []string{"developers", "developers"}}}, valid: false},
+		{name: "binding name that is too long", user: v1alpha1.User{Spec: v1alpha1.UserSpec{GroupMemberships: []string{strings.Repeat("a", 240)}}}, source: true, valid: false},
+		{name: "name that is too long for a label", userName: strings.Repeat("a", 64), valid: false},
+		{name: "membership ending before it starts", user: v1alpha1.User{Spec: v1alpha1.UserSpec{Memberships: []v1alpha1.GroupMembership{{Group: "developers", NotBefore: &notBefore, ExpiresAt: &expiresAt}}}}, valid: false},
+		{name: "token ttl below the minimum", user: v1alpha1.User{Spec: v1alpha1.UserSpec{Token: &v1alpha1.UserTokenSpec{TTL: &v3.Duration{Duration: time.Second}}}}, valid: false},
+		{name: "no ServiceAccount and no identity", user: v1alpha1.User{Spec: v1alpha1.UserSpec{ServiceAccount: &serviceAccount}}, valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := test.user.DeepCopy()
+			user.Name, user.Namespace = "jane", "team"
+			if test.userName != "" {
+				user.Name = test.userName
+			}
+			if test.source {
+				user.OwnerReferences = []v3.OwnerReference{{Kind: "SynchronisationSource", Name: "acme"}}
+			}
+
+			errs := ValidateUser(user, test.old, groupExists)
+			if valid := len(errs) == 0; valid != test.valid {
+				t.Errorf("ValidateUser() = %v, want valid %v", errs, test.valid)
+			}
+		})
+	}
+}
+
+func TestValidateGroup(t *testing.T) {
+	rules := []v4.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}}
+
+	tests := []struct {
+		name      string
+		groupName string
+		spec      v1alpha1.GroupSpec
+		valid     bool
+	}{
+		{name: "cluster group", spec: v1alpha1.GroupSpec{ClusterGroup: true, Permissions: rules}, valid: true},
+		{name: "namespaced group", spec: v1alpha1.GroupSpec{Namespaces: []string{"default"}, Permissions: rules}, valid: true},
+		{name: "namespaced group without namespaces", spec: v1alpha1.GroupSpec{Permissions: rules}, valid: false},
+		{name: "no permissions", spec: v1alpha1.GroupSpec{ClusterGroup: true}, valid: false},
+		{name: "invalid name", groupName: "Developers", spec: v1alpha1.GroupSpec{ClusterGroup: true, Permissions: rules}, valid: false},
+		{name: "name that is too long for a label", groupName: strings.Repeat("a", 64), spec: v1alpha1.GroupSpec{ClusterGroup: true, Permissions: rules}, valid: false},
+		{name: "rule without verbs", spec: v1alpha1.GroupSpec{ClusterGroup: true, Permissions: []v4.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}}}}, valid: false},
+		{name: "including itself", spec: v1alpha1.GroupSpec{Includes: []string{"developers"}}, valid: false},
+		{name: "aggregated group with namespaced Roles", spec: v1alpha1.GroupSpec{ClusterGroup: true, NamespacedRoles: true, AggregationLabels: []map[string]string{{"team": "a"}}}, valid: false},
+		{name: "empty aggregation selector", spec: v1alpha1.GroupSpec{ClusterGroup: true, AggregationLabels: []map[string]string{{}}}, valid: false},
+		{
+			name: "duplicate namespace permissions",
+			spec: v1alpha1.GroupSpec{NamespacePermissions: []v1alpha1.NamespacePermissions{
+				{Name: "ci", Namespaces: []string{"ci"}, Permissions: rules},
+				{Name: "ci", Namespaces: []string{"ci"}, Permissions: rules},
+			}},
+			valid: false,
+		},
+		{name: "namespace permissions with a role and rules", spec: v1alpha1.GroupSpec{NamespacePermissions: []v1alpha1.NamespacePermissions{{Name: "ci", Namespaces: []string{"ci"}, Permissions: rules, ClusterRoleRef: "view"}}}, valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			group := &v1alpha1.Group{
+				ObjectMeta: v3.ObjectMeta{Name: "developers", Namespace: "team"},
+				Spec:       test.spec,
+			}
+			if test.groupName != "" {
+				group.Name = test.groupName
+			}
+
+			errs := ValidateGroup(group)
+			if valid := len(errs) == 0; valid != test.valid {
+				t.Errorf("ValidateGroup() = %v, want valid %v", errs, test.valid)
+			}
+		})
+	}
+}